@@ -2,32 +2,140 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/cyrilix/robocar-base/cli"
+	"github.com/cyrilix/robocar-led/pkg/led"
 	"github.com/cyrilix/robocar-led/pkg/part"
 	"go.uber.org/zap"
 	"log"
 	"os"
+	"os/signal"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi/spireg"
+	"strings"
+	"syscall"
+	"time"
 )
 
 const (
 	DefaultClientId = "robocar-led"
 )
 
+const (
+	ledBackendGPIO   = "gpio"
+	ledBackendWS2812 = "ws2812"
+	ledBackendAPA102 = "apa102"
+	ledBackendSysfs  = "sysfs"
+	ledBackendSim    = "sim"
+)
+
+type ledConfig struct {
+	backend                   string
+	pwmFrequency              int
+	pwmMode                   string
+	pinRed, pinGreen, pinBlue string
+	commonAnode               bool
+	pixels                    int
+	spiPort                   string
+	apa102Brightness          int
+	sysfsRoot, sysfsName      string
+	calibration               led.Calibration
+	calibrationFile           string
+}
+
+// modeConfig holds settings of led mode, reloaded on SIGHUP
+type modeConfig struct {
+	mode                       string
+	rulesFile                  string
+	theme, paletteFile, colors string
+	brakeLevels                string
+	brakeHysteresis            float64
+	brakeMinHold, brakeHold    time.Duration
+	decelerationDrop           float64
+	decelerationWindow         time.Duration
+	throttleGradient           string
+}
+
 func main() {
 	var mqttBroker, username, password, clientId string
-	var driveModeTopic, recordTopic, speedZoneTopic, throttleTopic string
-	var enableSpeedZoneMode bool
+	var driveModeTopic, recordTopic, speedZoneTopic, throttleTopic, brightnessTopic, configTopic, overrideTopic, stateTopic, stateJSONTopic string
+	var ledCfg ledConfig
+	var modeCfg modeConfig
+	var fadeDuration time.Duration
+	var brightness float64
 
 	mqttQos := cli.InitIntFlag("MQTT_QOS", 0)
 	_, mqttRetain := os.LookupEnv("MQTT_RETAIN")
 
+	cli.SetDefaultValueFromEnv(&ledCfg.backend, "LED_BACKEND", ledBackendGPIO)
+	ledCfg.pwmFrequency = cli.InitIntFlag("LED_PWM_FREQUENCY", int(led.DefaultPWMFrequency/physic.Hertz))
+	cli.SetDefaultValueFromEnv(&ledCfg.pwmMode, "LED_PWM_MODE", led.PWMModeAuto.String())
+	_, ledCfg.commonAnode = os.LookupEnv("LED_COMMON_ANODE")
+	cli.SetDefaultValueFromEnv(&ledCfg.pinRed, "LED_PIN_RED", "GPIO23")
+	cli.SetDefaultValueFromEnv(&ledCfg.pinGreen, "LED_PIN_GREEN", "GPIO24")
+	cli.SetDefaultValueFromEnv(&ledCfg.pinBlue, "LED_PIN_BLUE", "GPIO25")
+	ledCfg.pixels = cli.InitIntFlag("LED_PIXELS", 8)
+	cli.SetDefaultValueFromEnv(&ledCfg.spiPort, "LED_SPI_PORT", "")
+	ledCfg.apa102Brightness = cli.InitIntFlag("LED_APA102_BRIGHTNESS", led.MaxAPA102Brightness)
+	cli.SetDefaultValueFromEnv(&ledCfg.sysfsRoot, "LED_SYSFS_ROOT", led.DefaultSysfsRoot)
+	ledCfg.calibration.Gamma = cli.InitFloat64Flag("LED_GAMMA", led.DefaultCalibration.Gamma)
+	ledCfg.calibration.Red = cli.InitFloat64Flag("LED_SCALE_RED", led.DefaultCalibration.Red)
+	ledCfg.calibration.Green = cli.InitFloat64Flag("LED_SCALE_GREEN", led.DefaultCalibration.Green)
+	ledCfg.calibration.Blue = cli.InitFloat64Flag("LED_SCALE_BLUE", led.DefaultCalibration.Blue)
+
+	fadeDuration = initDurationFlag("LED_FADE_DURATION", 0)
+	brightness = cli.InitFloat64Flag("LED_BRIGHTNESS", 1)
+	cli.SetDefaultValueFromEnv(&modeCfg.mode, "LED_MODE", string(part.LedModeBrake))
+	cli.SetDefaultValueFromEnv(&modeCfg.theme, "LED_THEME", part.DefaultTheme)
+	modeCfg.brakeHysteresis = cli.InitFloat64Flag("LED_BRAKE_HYSTERESIS", part.DefaultBrakeHysteresis)
+	modeCfg.brakeMinHold = initDurationFlag("LED_BRAKE_MIN_HOLD", part.DefaultBrakeMinHold)
+	modeCfg.brakeHold = initDurationFlag("LED_BRAKE_HOLD", 0)
+	modeCfg.decelerationDrop = cli.InitFloat64Flag("LED_DECELERATION_DROP", 0)
+	modeCfg.decelerationWindow = initDurationFlag("LED_DECELERATION_WINDOW", part.DefaultDecelerationWindow)
+
 	cli.InitMqttFlags(DefaultClientId, &mqttBroker, &username, &password, &clientId, &mqttQos, &mqttRetain)
 
 	flag.StringVar(&driveModeTopic, "mqtt-topic-drive-mode", os.Getenv("MQTT_TOPIC_DRIVE_MODE"), "Mqtt topic that contains DriveMode value, use MQTT_TOPIC_DRIVE_MODE if args not set")
 	flag.StringVar(&recordTopic, "mqtt-topic-record", os.Getenv("MQTT_TOPIC_RECORD"), "Mqtt topic that contains video recording state, use MQTT_TOPIC_RECORD if args not set")
 	flag.StringVar(&speedZoneTopic, "mqtt-topic-speed-zone", os.Getenv("MQTT_TOPIC_SPEED_ZONE"), "Mqtt topic that contains speed zone, use MQTT_TOPIC_SPEED_ZONE if args not set")
 	flag.StringVar(&throttleTopic, "mqtt-topic-throttle", os.Getenv("MQTT_TOPIC_THROTTLE"), "Mqtt topic that contains throttle, use MQTT_TOPIC_THROTTLE if args not set")
-	flag.BoolVar(&enableSpeedZoneMode, "enable-speedzone-mode", false, "Enable speed-zone mode")
+	flag.StringVar(&brightnessTopic, "mqtt-topic-brightness", os.Getenv("MQTT_TOPIC_BRIGHTNESS"), "Mqtt topic that contains led brightness (0-1) as text, use MQTT_TOPIC_BRIGHTNESS if args not set")
+	flag.StringVar(&configTopic, "mqtt-topic-config", os.Getenv("MQTT_TOPIC_CONFIG"), "Mqtt topic that contains json led configuration to apply at runtime, use MQTT_TOPIC_CONFIG if args not set")
+	flag.StringVar(&overrideTopic, "mqtt-topic-override", os.Getenv("MQTT_TOPIC_OVERRIDE"), "Mqtt topic that contains json commands to force led color and blink ({\"color\": \"white\", \"blink\": \"10hz\", \"ttl\": \"30s\"}, {\"clear\": true} to resume), use MQTT_TOPIC_OVERRIDE if args not set")
+	flag.StringVar(&stateTopic, "mqtt-topic-led-state", os.Getenv("MQTT_TOPIC_LED_STATE"), "Mqtt topic where to publish led state (protobuf LedStateMessage, see pkg/ledevents/ledstate.proto) each time it changes, use MQTT_TOPIC_LED_STATE if args not set")
+	flag.StringVar(&stateJSONTopic, "mqtt-topic-led-state-json", os.Getenv("MQTT_TOPIC_LED_STATE_JSON"), "Mqtt topic where to publish led state as json each time it changes, use MQTT_TOPIC_LED_STATE_JSON if args not set")
+	flag.StringVar(&modeCfg.mode, "led-mode", modeCfg.mode, fmt.Sprintf("Strategy that computes led color from car state (%v), use LED_MODE if args not set", strings.Join(part.StrategyNames(), "|")))
+	flag.StringVar(&modeCfg.rulesFile, "rules", os.Getenv("LED_RULES"), "Json or yaml (.yaml, .yml) file with ordered rules that map car state to led color and blink, it enables rules mode, use LED_RULES if args not set")
+	flag.DurationVar(&fadeDuration, "led-fade-duration", fadeDuration, "Duration of fade between led colors, no fade if 0, use LED_FADE_DURATION if args not set")
+	flag.Float64Var(&brightness, "led-brightness", brightness, "Global brightness (0-1) of led, use LED_BRIGHTNESS if args not set")
+	flag.StringVar(&modeCfg.theme, "led-theme", modeCfg.theme, fmt.Sprintf("Built-in palette of led colors (%v), use LED_THEME if args not set", strings.Join(part.ThemeNames(), "|")))
+	flag.StringVar(&modeCfg.paletteFile, "led-palette-file", os.Getenv("LED_PALETTE_FILE"), "Json file with colors that override theme ({\"user\": \"#00FF00\", \"brake_max\": \"purple\", ...}), use LED_PALETTE_FILE if args not set")
+	flag.StringVar(&modeCfg.colors, "led-colors", os.Getenv("LED_COLORS"), "Comma separated colors that override theme and palette file (ex: user=#FF8800,pilot=rgb(0, 0, 255)), use LED_COLORS if args not set")
+	flag.StringVar(&modeCfg.brakeLevels, "led-brake-levels", os.Getenv("LED_BRAKE_LEVELS"), "Comma separated brake levels, from lightest to hardest brake, displayed when throttle <= threshold (ex: -0.05=white,-0.5=red), thresholds -0.05,-0.25,-0.5,-0.75 with palette brake colors if empty, use LED_BRAKE_LEVELS if args not set")
+	flag.Float64Var(&modeCfg.brakeHysteresis, "led-brake-hysteresis", modeCfg.brakeHysteresis, "Throttle margin above threshold needed to release a brake level, use LED_BRAKE_HYSTERESIS if args not set")
+	flag.DurationVar(&modeCfg.brakeMinHold, "led-brake-min-hold", modeCfg.brakeMinHold, "Duration after which a brake level is released without hysteresis margin, use LED_BRAKE_MIN_HOLD if args not set")
+	cli.SetDefaultValueFromEnv(&modeCfg.throttleGradient, "LED_THROTTLE_GRADIENT", part.DefaultThrottleGradient.String())
+	flag.StringVar(&modeCfg.throttleGradient, "led-throttle-gradient", modeCfg.throttleGradient, "Comma separated gradient stops throttle=color used by throttle gradient mode, use LED_THROTTLE_GRADIENT if args not set")
+	flag.DurationVar(&modeCfg.brakeHold, "led-brake-hold", modeCfg.brakeHold, "Duration brake color stays displayed after braking ends, use LED_BRAKE_HOLD if args not set")
+	flag.Float64Var(&modeCfg.decelerationDrop, "led-deceleration-drop", modeCfg.decelerationDrop, "Throttle drop within deceleration window that is displayed as a brake, disabled if 0, use LED_DECELERATION_DROP if args not set")
+	flag.DurationVar(&modeCfg.decelerationWindow, "led-deceleration-window", modeCfg.decelerationWindow, "Duration over which throttle drop is measured, use LED_DECELERATION_WINDOW if args not set")
+	flag.StringVar(&ledCfg.backend, "led-backend", ledCfg.backend, "Led hardware to drive (gpio|ws2812|apa102|sysfs|sim), sim logs led changes without hardware, use LED_BACKEND if args not set")
+	flag.IntVar(&ledCfg.pwmFrequency, "led-pwm-frequency", ledCfg.pwmFrequency, "Frequency (Hz) of pwm signal used to render led intensities, use LED_PWM_FREQUENCY if args not set")
+	flag.StringVar(&ledCfg.pwmMode, "led-pwm-mode", ledCfg.pwmMode, "Pwm implementation used on led pins (auto|software|onoff), auto uses hardware pwm when supported by pin, use LED_PWM_MODE if args not set")
+	flag.StringVar(&ledCfg.pinRed, "led-pin-red", ledCfg.pinRed, "Gpio pin name wired to red channel, use LED_PIN_RED if args not set")
+	flag.StringVar(&ledCfg.pinGreen, "led-pin-green", ledCfg.pinGreen, "Gpio pin name wired to green channel, use LED_PIN_GREEN if args not set")
+	flag.StringVar(&ledCfg.pinBlue, "led-pin-blue", ledCfg.pinBlue, "Gpio pin name wired to blue channel, use LED_PIN_BLUE if args not set")
+	flag.BoolVar(&ledCfg.commonAnode, "led-common-anode", ledCfg.commonAnode, "Led is wired with common anode (channels lit on low level), if not set, true if LED_COMMON_ANODE env variable is set")
+	flag.IntVar(&ledCfg.pixels, "led-pixels", ledCfg.pixels, "Number of pixels of led strip, use LED_PIXELS if args not set")
+	flag.StringVar(&ledCfg.spiPort, "led-spi-port", ledCfg.spiPort, "Spi port name wired to led strip, first available port if empty, use LED_SPI_PORT if args not set")
+	flag.IntVar(&ledCfg.apa102Brightness, "led-apa102-brightness", ledCfg.apa102Brightness, "Global brightness (0-31) of apa102 strip, use LED_APA102_BRIGHTNESS if args not set")
+	flag.StringVar(&ledCfg.sysfsRoot, "led-sysfs-root", ledCfg.sysfsRoot, "Directory of kernel led class, use LED_SYSFS_ROOT if args not set")
+	flag.StringVar(&ledCfg.sysfsName, "led-sysfs-name", os.Getenv("LED_SYSFS_NAME"), "Name of kernel led to drive, use LED_SYSFS_NAME if args not set")
+	flag.Float64Var(&ledCfg.calibration.Gamma, "led-gamma", ledCfg.calibration.Gamma, "Gamma correction applied to led channels, use LED_GAMMA if args not set")
+	flag.Float64Var(&ledCfg.calibration.Red, "led-scale-red", ledCfg.calibration.Red, "Scale factor (0-1) of red channel to balance white, use LED_SCALE_RED if args not set")
+	flag.Float64Var(&ledCfg.calibration.Green, "led-scale-green", ledCfg.calibration.Green, "Scale factor (0-1) of green channel to balance white, use LED_SCALE_GREEN if args not set")
+	flag.Float64Var(&ledCfg.calibration.Blue, "led-scale-blue", ledCfg.calibration.Blue, "Scale factor (0-1) of blue channel to balance white, use LED_SCALE_BLUE if args not set")
+	flag.StringVar(&ledCfg.calibrationFile, "led-calibration-file", os.Getenv("LED_CALIBRATION_FILE"), "Json file with gamma and channel scales ({\"gamma\": 2.2, \"red\": 1, \"green\": 0.6, \"blue\": 0.8}), it overrides gamma and scale args, use LED_CALIBRATION_FILE if args not set")
 
 	logLevel := zap.LevelFlag("log", zap.InfoLevel, "log level")
 	flag.Parse()
@@ -56,13 +164,36 @@ func main() {
 	}
 	defer client.Disconnect(50)
 
-	mode := part.LedModeBrake
-	if enableSpeedZoneMode {
-		mode = part.LedModeSpeedZone
+	if brightness < 0 || brightness > 1 {
+		zap.S().Fatalf("invalid led brightness %v, must be in range [0, 1]", brightness)
 	}
-	p := part.NewPart(client, driveModeTopic, recordTopic, speedZoneTopic, throttleTopic, mode)
+	if mqttQos < 0 || mqttQos > 2 {
+		zap.S().Fatalf("invalid mqtt qos %v, must be 0, 1 or 2", mqttQos)
+	}
+	mode, strategyCfg, err := newStrategyConfig(&modeCfg)
+	if err != nil {
+		zap.S().Fatalf("invalid led config: %v", err)
+	}
+
+	l, err := newLed(&ledCfg)
+	if err != nil {
+		zap.S().Fatalf("unable to init led: %v", err)
+	}
+	p := part.NewPart(client, l, driveModeTopic, recordTopic, speedZoneTopic, throttleTopic, mode,
+		part.WithFadeDuration(fadeDuration),
+		part.WithBrightness(brightness),
+		part.WithBrightnessTopic(brightnessTopic),
+		part.WithConfigTopic(configTopic),
+		part.WithOverrideTopic(overrideTopic),
+		part.WithStateTopic(stateTopic),
+		part.WithStateJSONTopic(stateJSONTopic),
+		part.WithPublishOptions(byte(mqttQos), mqttRetain),
+		part.WithStrategyConfig(strategyCfg),
+	)
 	defer p.Stop()
 
+	go reloadOnSignal(p, &modeCfg)
+
 	cli.HandleExit(p)
 
 	err = p.Start()
@@ -70,3 +201,166 @@ func main() {
 		zap.S().Fatalf("unable to start service: %v", err)
 	}
 }
+
+func initDurationFlag(key string, defValue time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return defValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		zap.S().Panicf("invalid duration value for %v: %v", key, err)
+	}
+	return d
+}
+
+// newStrategyConfig builds led mode and strategy config from settings, rules file enables rules mode
+func newStrategyConfig(cfg *modeConfig) (part.LedMode, part.StrategyConfig, error) {
+	palette, err := newPalette(cfg.theme, cfg.paletteFile, cfg.colors)
+	if err != nil {
+		return "", part.StrategyConfig{}, fmt.Errorf("invalid led palette: %v", err)
+	}
+	gradient, err := led.ParseGradient(cfg.throttleGradient)
+	if err != nil {
+		return "", part.StrategyConfig{}, fmt.Errorf("invalid throttle gradient: %v", err)
+	}
+	strategyCfg := part.StrategyConfig{
+		Palette:            &palette,
+		BrakeHysteresis:    float32(cfg.brakeHysteresis),
+		BrakeMinHold:       cfg.brakeMinHold,
+		BrakeHold:          cfg.brakeHold,
+		DecelerationDrop:   float32(cfg.decelerationDrop),
+		DecelerationWindow: cfg.decelerationWindow,
+		ThrottleGradient:   gradient,
+	}
+	if cfg.brakeLevels != "" {
+		strategyCfg.BrakeLevels, err = part.ParseBrakeLevels(cfg.brakeLevels)
+		if err != nil {
+			return "", part.StrategyConfig{}, fmt.Errorf("invalid brake levels: %v", err)
+		}
+	}
+
+	mode := part.LedMode(cfg.mode)
+	if cfg.rulesFile != "" {
+		strategyCfg.Rules, err = part.LoadRules(cfg.rulesFile)
+		if err != nil {
+			return "", part.StrategyConfig{}, fmt.Errorf("invalid led rules: %v", err)
+		}
+		mode = part.LedModeRules
+	}
+	if _, err := part.NewStrategy(string(mode), strategyCfg); err != nil {
+		return "", part.StrategyConfig{}, fmt.Errorf("invalid led mode: %v", err)
+	}
+	return mode, strategyCfg, nil
+}
+
+// reloadOnSignal reads again files of led config on SIGHUP, current config is kept if new one is invalid
+func reloadOnSignal(p *part.LedPart, cfg *modeConfig) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		mode, strategyCfg, err := newStrategyConfig(cfg)
+		if err == nil {
+			err = p.Reload(mode, strategyCfg)
+		}
+		if err != nil {
+			zap.S().Errorf("unable to reload led config, keep current one: %v", err)
+			continue
+		}
+		zap.S().Infof("led config reloaded, mode %v", mode)
+	}
+}
+
+// newPalette builds palette from theme, then applies colors of file and overrides
+func newPalette(theme, file, overrides string) (part.Palette, error) {
+	palette, err := part.Theme(theme)
+	if err != nil {
+		return part.Palette{}, err
+	}
+	if file != "" {
+		palette, err = part.LoadPalette(file, palette)
+		if err != nil {
+			return part.Palette{}, err
+		}
+	}
+	return palette.WithOverrides(overrides)
+}
+
+func newLed(cfg *ledConfig) (led.ColoredLed, error) {
+	calibration := cfg.calibration
+	if cfg.calibrationFile != "" {
+		c, err := led.LoadCalibration(cfg.calibrationFile)
+		if err != nil {
+			return nil, err
+		}
+		calibration = c
+	}
+	if err := calibration.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid led calibration: %v", err)
+	}
+
+	l, err := newLedBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := l.(led.Calibrated); ok {
+		if err := c.SetCalibration(calibration); err != nil {
+			return nil, err
+		}
+	} else if calibration != led.DefaultCalibration {
+		zap.S().Warnf("led backend '%v' doesn't support calibration", cfg.backend)
+	}
+	return l, nil
+}
+
+func newLedBackend(cfg *ledConfig) (led.ColoredLed, error) {
+	switch cfg.backend {
+	case ledBackendGPIO:
+		pwmMode, err := led.ParsePWMMode(cfg.pwmMode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid led pwm mode: %v", err)
+		}
+		polarity := led.ActiveHigh
+		if cfg.commonAnode {
+			polarity = led.ActiveLow
+		}
+		return led.NewWithPinNames(cfg.pinRed, cfg.pinGreen, cfg.pinBlue,
+			led.WithPWMFrequency(physic.Frequency(cfg.pwmFrequency)*physic.Hertz),
+			led.WithPWMMode(pwmMode),
+			led.WithPolarity(polarity),
+		)
+	case ledBackendWS2812:
+		if err := led.Open(); err != nil {
+			return nil, err
+		}
+		port, err := spireg.Open(cfg.spiPort)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open spi port '%v': %v", cfg.spiPort, err)
+		}
+		return led.NewWS2812(port, cfg.pixels)
+	case ledBackendAPA102:
+		if cfg.apa102Brightness < 0 || cfg.apa102Brightness > led.MaxAPA102Brightness {
+			return nil, fmt.Errorf("invalid apa102 brightness %v", cfg.apa102Brightness)
+		}
+		if err := led.Open(); err != nil {
+			return nil, err
+		}
+		port, err := spireg.Open(cfg.spiPort)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open spi port '%v': %v", cfg.spiPort, err)
+		}
+		s, err := led.NewAPA102(port, cfg.pixels)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.SetBrightness(uint8(cfg.apa102Brightness)); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case ledBackendSysfs:
+		return led.NewSysfsLed(cfg.sysfsRoot, cfg.sysfsName)
+	case ledBackendSim:
+		return led.NewSimLed(), nil
+	}
+	return nil, fmt.Errorf("unknown led backend '%v'", cfg.backend)
+}
@@ -10,6 +10,7 @@ require (
 	github.com/eclipse/paho.mqtt.golang v1.4.3
 	go.uber.org/zap v1.26.0
 	google.golang.org/protobuf v1.31.0
+	gopkg.in/yaml.v3 v3.0.1
 	periph.io/x/conn/v3 v3.7.0
 	periph.io/x/host/v3 v3.8.2
 )
@@ -0,0 +1,208 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock abstracts time so that timing logic can be driven manually in tests
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f once d elapsed, C() of returned timer is nil. Fake clock calls f from Advance.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the equivalent of time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// New returns a clock backed by time package
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{t: time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{t: time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r *realTimer) C() <-chan time.Time {
+	return r.t.C
+}
+
+func (r *realTimer) Stop() bool {
+	return r.t.Stop()
+}
+
+func (r *realTimer) Reset(d time.Duration) bool {
+	return r.t.Reset(d)
+}
+
+// Fake is a manual clock, time only moves forward when Advance is called
+type Fake struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFake(now time.Time) *Fake {
+	f := Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return &f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	f.timers = append(f.timers, &t)
+	t.arm(d)
+	return &t
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := fakeTimer{clock: f, fn: fn}
+	f.timers = append(f.timers, &t)
+	t.arm(d)
+	return &t
+}
+
+// Advance moves clock forward and fires, in order, timers that expire before new time
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	end := f.now.Add(d)
+	for {
+		expired := f.expiredTimers(end)
+		if len(expired) == 0 {
+			break
+		}
+		t := expired[0]
+		f.now = t.deadline
+		if fn := t.fire(); fn != nil {
+			// fn can use clock
+			f.mu.Unlock()
+			fn()
+			f.mu.Lock()
+		}
+	}
+	f.now = end
+}
+
+// BlockUntil waits until n timers are armed, it allows to wait for goroutines to be waiting on clock
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.activeTimers() < n {
+		f.cond.Wait()
+	}
+}
+
+// expiredTimers returns active timers with deadline before end, sorted by deadline. Must be called with mu locked
+func (f *Fake) expiredTimers(end time.Time) []*fakeTimer {
+	var expired []*fakeTimer
+	for _, t := range f.timers {
+		if t.active && !t.deadline.After(end) {
+			expired = append(expired, t)
+		}
+	}
+	sort.SliceStable(expired, func(i, j int) bool {
+		return expired[i].deadline.Before(expired[j].deadline)
+	})
+	return expired
+}
+
+// activeTimers must be called with mu locked
+func (f *Fake) activeTimers() int {
+	n := 0
+	for _, t := range f.timers {
+		if t.active {
+			n += 1
+		}
+	}
+	return n
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+	active   bool
+	// fn is called instead of sending on c for AfterFunc timers
+	fn func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.arm(d)
+	return wasActive
+}
+
+// arm must be called with clock mu locked
+func (t *fakeTimer) arm(d time.Duration) {
+	t.deadline = t.clock.now.Add(d)
+	t.active = true
+	if d <= 0 {
+		if fn := t.fire(); fn != nil {
+			go fn()
+		}
+		return
+	}
+	t.clock.cond.Broadcast()
+}
+
+// fire returns function to call for AfterFunc timers, must be called with clock mu locked
+func (t *fakeTimer) fire() func() {
+	t.active = false
+	if t.fn != nil {
+		return t.fn
+	}
+	select {
+	case t.c <- t.deadline:
+	default:
+	}
+	return nil
+}
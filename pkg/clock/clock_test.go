@@ -0,0 +1,141 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func fired(t Timer) bool {
+	select {
+	case <-t.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFake_Advance(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	t1 := c.NewTimer(10 * time.Millisecond)
+	t2 := c.NewTimer(20 * time.Millisecond)
+
+	c.Advance(5 * time.Millisecond)
+	if fired(t1) || fired(t2) {
+		t.Errorf("timers fired before deadline")
+	}
+	if c.Now() != start.Add(5*time.Millisecond) {
+		t.Errorf("Now(): %v, wants %v", c.Now(), start.Add(5*time.Millisecond))
+	}
+
+	c.Advance(5 * time.Millisecond)
+	if !fired(t1) {
+		t.Errorf("timer not fired at deadline")
+	}
+	if fired(t2) {
+		t.Errorf("timer fired before deadline")
+	}
+
+	c.Advance(time.Hour)
+	if !fired(t2) {
+		t.Errorf("timer not fired after deadline")
+	}
+	if fired(t1) {
+		t.Errorf("timer fired twice")
+	}
+}
+
+func TestFake_StopReset(t *testing.T) {
+	c := NewFake(time.Now())
+
+	timer := c.NewTimer(10 * time.Millisecond)
+	if !timer.Stop() {
+		t.Errorf("Stop() on active timer must return true")
+	}
+	c.Advance(20 * time.Millisecond)
+	if fired(timer) {
+		t.Errorf("stopped timer fired")
+	}
+
+	if timer.Reset(10 * time.Millisecond) {
+		t.Errorf("Reset() on stopped timer must return false")
+	}
+	c.Advance(10 * time.Millisecond)
+	if !fired(timer) {
+		t.Errorf("reset timer not fired")
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	c := NewFake(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.BlockUntil(1)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("BlockUntil returned without active timer")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.NewTimer(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("BlockUntil not released by new timer")
+	}
+}
+
+func TestFake_AfterFunc(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	var calls []time.Time
+	var timer Timer
+	timer = c.AfterFunc(10*time.Millisecond, func() {
+		calls = append(calls, c.Now())
+		if len(calls) < 2 {
+			timer.Reset(10 * time.Millisecond)
+		}
+	})
+
+	c.Advance(9 * time.Millisecond)
+	if len(calls) != 0 {
+		t.Errorf("function called before deadline")
+	}
+	// Function is called synchronously, it can use clock
+	c.Advance(time.Hour)
+	if len(calls) != 2 || calls[0] != start.Add(10*time.Millisecond) || calls[1] != start.Add(20*time.Millisecond) {
+		t.Errorf("calls: %v, wants at 10ms and 20ms", calls)
+	}
+
+	timer.Reset(time.Millisecond)
+	timer.Stop()
+	c.Advance(time.Second)
+	if len(calls) != 2 {
+		t.Errorf("stopped timer called function")
+	}
+}
+
+func TestNew(t *testing.T) {
+	c := New()
+	timer := c.NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Errorf("real timer not fired")
+	}
+	called := make(chan struct{})
+	c.AfterFunc(time.Millisecond, func() { close(called) })
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Errorf("real AfterFunc not called")
+	}
+	if time.Since(c.Now()) > time.Second {
+		t.Errorf("Now() doesn't return current time")
+	}
+}
@@ -0,0 +1,243 @@
+package led
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"math"
+	"sync"
+	"time"
+)
+
+const DefaultFrameInterval = 20 * time.Millisecond
+
+// Easing maps linear progress of a transition in [0, 1] to eased progress
+type Easing func(t float64) float64
+
+func EaseLinear(t float64) float64 {
+	return t
+}
+
+// EaseInOut starts and ends transition slowly (sine curve)
+func EaseInOut(t float64) float64 {
+	return (1 - math.Cos(math.Pi*t)) / 2
+}
+
+// Keyframe describes a transition from previous color to Color during Duration
+type Keyframe struct {
+	Color    Color
+	Duration time.Duration
+	// Easing is linear if nil
+	Easing Easing
+}
+
+// Animation plays Keyframes sequence Loops times (forever if Loops <= 0), first transition starts
+// from current led color
+type Animation struct {
+	Keyframes []Keyframe
+	Loops     int
+}
+
+func (a Animation) validate() error {
+	if len(a.Keyframes) == 0 {
+		return fmt.Errorf("animation without keyframe")
+	}
+	var total time.Duration
+	for i, k := range a.Keyframes {
+		if k.Duration < 0 {
+			return fmt.Errorf("invalid duration %v for keyframe %v", k.Duration, i)
+		}
+		total += k.Duration
+	}
+	if a.Loops <= 0 && total == 0 {
+		return fmt.Errorf("infinite animation must have a duration")
+	}
+	return nil
+}
+
+// Fade transitions from current color to color
+func Fade(color Color, duration time.Duration, easing Easing) Animation {
+	return Animation{
+		Keyframes: []Keyframe{{Color: color, Duration: duration, Easing: easing}},
+		Loops:     1,
+	}
+}
+
+// Breathe fades color in and out forever, period is the duration of a full cycle
+func Breathe(color Color, period time.Duration) Animation {
+	return Animation{
+		Keyframes: []Keyframe{
+			{Color: color, Duration: period / 2, Easing: EaseInOut},
+			{Color: ColorBlack, Duration: period / 2, Easing: EaseInOut},
+		},
+	}
+}
+
+// ColorCycle fades forever from a color to the next one, each transition lasts step
+func ColorCycle(step time.Duration, colors ...Color) Animation {
+	keyframes := make([]Keyframe, 0, len(colors))
+	for _, c := range colors {
+		keyframes = append(keyframes, Keyframe{Color: c, Duration: step, Easing: EaseLinear})
+	}
+	return Animation{Keyframes: keyframes}
+}
+
+// Animator plays animations on a ColoredLed, it implements ColoredLed so that a SetColor call
+// cancels running animation
+type Animator struct {
+	led           ColoredLed
+	clock         clock.Clock
+	frameInterval time.Duration
+
+	muAnimation sync.Mutex
+	cancel      chan interface{}
+	done        chan interface{}
+	// target is the last color of running animation
+	target Color
+
+	muColor      sync.RWMutex
+	currentColor Color
+}
+
+func NewAnimator(l ColoredLed, clk clock.Clock) *Animator {
+	return &Animator{
+		led:           l,
+		clock:         clk,
+		frameInterval: DefaultFrameInterval,
+		currentColor:  ColorBlack,
+		target:        ColorBlack,
+	}
+}
+
+// Play cancels running animation and starts anim
+func (a *Animator) Play(anim Animation) error {
+	if err := anim.validate(); err != nil {
+		return fmt.Errorf("invalid animation: %v", err)
+	}
+
+	a.muAnimation.Lock()
+	defer a.muAnimation.Unlock()
+	a.stop()
+
+	a.target = anim.Keyframes[len(anim.Keyframes)-1].Color
+	a.cancel = make(chan interface{})
+	a.done = make(chan interface{})
+	go a.run(anim, a.Color(), a.cancel, a.done)
+	return nil
+}
+
+// FadeTo fades to color, it does nothing if led already shows or is fading to color
+func (a *Animator) FadeTo(color Color, duration time.Duration) {
+	a.muAnimation.Lock()
+	if a.target == color {
+		a.muAnimation.Unlock()
+		return
+	}
+	a.muAnimation.Unlock()
+
+	if duration <= 0 {
+		a.SetColor(color)
+		return
+	}
+	// Fade animation is always valid
+	_ = a.Play(Fade(color, duration, EaseInOut))
+}
+
+// Wait blocks until running animation ends, it never returns for an infinite animation
+func (a *Animator) Wait() {
+	a.muAnimation.Lock()
+	done := a.done
+	a.muAnimation.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Stop cancels running animation, led keeps its current color
+func (a *Animator) Stop() {
+	a.muAnimation.Lock()
+	defer a.muAnimation.Unlock()
+	a.stop()
+	a.target = a.Color()
+}
+
+// SetColor cancels running animation and applies color
+func (a *Animator) SetColor(color Color) {
+	a.muAnimation.Lock()
+	defer a.muAnimation.Unlock()
+	a.stop()
+	a.target = color
+	a.render(color)
+}
+
+func (a *Animator) SetBlink(freq float64) {
+	a.led.SetBlink(freq)
+}
+
+func (a *Animator) SetBlinkPattern(pattern BlinkPattern) {
+	a.led.SetBlinkPattern(pattern)
+}
+
+func (a *Animator) Color() Color {
+	a.muColor.RLock()
+	defer a.muColor.RUnlock()
+	return a.currentColor
+}
+
+// stop cancels running animation, must be called with muAnimation locked
+func (a *Animator) stop() {
+	if a.cancel == nil {
+		return
+	}
+	close(a.cancel)
+	<-a.done
+	a.cancel = nil
+	a.done = nil
+}
+
+func (a *Animator) render(color Color) {
+	a.muColor.Lock()
+	defer a.muColor.Unlock()
+	a.currentColor = color
+	a.led.SetColor(color)
+}
+
+func (a *Animator) run(anim Animation, from Color, cancel <-chan interface{}, done chan<- interface{}) {
+	defer close(done)
+
+	timer := a.clock.NewTimer(a.frameInterval)
+	timer.Stop()
+	defer timer.Stop()
+
+	for loop := 0; anim.Loops <= 0 || loop < anim.Loops; loop++ {
+		for _, k := range anim.Keyframes {
+			easing := k.Easing
+			if easing == nil {
+				easing = EaseLinear
+			}
+
+			start := a.clock.Now()
+			for {
+				elapsed := a.clock.Now().Sub(start)
+				if elapsed >= k.Duration {
+					a.render(k.Color)
+					break
+				}
+				a.render(Lerp(from, k.Color, easing(float64(elapsed)/float64(k.Duration))))
+
+				timer.Reset(a.frameInterval)
+				select {
+				case <-timer.C():
+				case <-cancel:
+					return
+				}
+			}
+			from = k.Color
+
+			select {
+			case <-cancel:
+				return
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package led
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"testing"
+	"time"
+)
+
+func TestAnimator_Fade(t *testing.T) {
+	l := NewSimLed()
+	clk := clock.NewFake(time.Now())
+	a := NewAnimator(l, clk)
+
+	if err := a.Play(Fade(ColorRed, 100*time.Millisecond, EaseLinear)); err != nil {
+		t.Fatalf("unable to play animation: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(DefaultFrameInterval)
+	}
+	clk.BlockUntil(1)
+	clk.Advance(DefaultFrameInterval)
+	a.Wait()
+
+	if l.Color() != ColorRed {
+		t.Errorf("color after fade: %v, wants %v", l.Color(), ColorRed)
+	}
+	// first frame (black) is the current led color
+	expected := []int{51, 102, 153, 204, 255}
+	events := l.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("%v colors rendered, wants %v: %v", len(events), len(expected), events)
+	}
+	for i, red := range expected {
+		if events[i].Color.Red != red {
+			t.Errorf("frame %v: red %v, wants %v", i, events[i].Color.Red, red)
+		}
+	}
+}
+
+func TestAnimator_FadeToSameTarget(t *testing.T) {
+	l := NewSimLed()
+	clk := clock.NewFake(time.Now())
+	a := NewAnimator(l, clk)
+
+	a.FadeTo(ColorBlue, 50*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(DefaultFrameInterval)
+	}
+	a.Wait()
+	if a.Color() != ColorBlue {
+		t.Fatalf("color after fade: %v, wants %v", a.Color(), ColorBlue)
+	}
+	events := len(l.Events())
+
+	a.FadeTo(ColorBlue, 50*time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
+	if len(l.Events()) != events {
+		t.Errorf("fade to current color must not restart animation: %v", l.Events())
+	}
+
+	a.FadeTo(ColorGreen, 0)
+	if l.Color() != ColorGreen {
+		t.Errorf("color after fade without duration: %v, wants %v", l.Color(), ColorGreen)
+	}
+}
+
+func TestAnimator_SetColorCancelsAnimation(t *testing.T) {
+	l := NewSimLed()
+	clk := clock.NewFake(time.Now())
+	a := NewAnimator(l, clk)
+
+	if err := a.Play(Breathe(ColorWhite, 40*time.Millisecond)); err != nil {
+		t.Fatalf("unable to play animation: %v", err)
+	}
+	clk.BlockUntil(1)
+	clk.Advance(DefaultFrameInterval)
+	clk.BlockUntil(1)
+	a.SetColor(ColorBlue)
+	events := len(l.Events())
+	clk.Advance(100 * time.Millisecond)
+
+	if l.Color() != ColorBlue {
+		t.Errorf("color: %v, wants %v", l.Color(), ColorBlue)
+	}
+	if len(l.Events()) != events {
+		t.Errorf("animation still running after SetColor")
+	}
+}
+
+func TestAnimator_KeyframeLoops(t *testing.T) {
+	l := NewSimLed()
+	a := NewAnimator(l, clock.NewFake(time.Now()))
+
+	err := a.Play(Animation{
+		Keyframes: []Keyframe{{Color: ColorRed}, {Color: ColorGreen}},
+		Loops:     2,
+	})
+	if err != nil {
+		t.Fatalf("unable to play animation: %v", err)
+	}
+	a.Wait()
+
+	expected := []Color{ColorRed, ColorGreen, ColorRed, ColorGreen}
+	events := l.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("%v colors rendered, wants %v: %v", len(events), len(expected), events)
+	}
+	for i, c := range expected {
+		if events[i].Color != c {
+			t.Errorf("color %v: %v, wants %v", i, events[i].Color, c)
+		}
+	}
+}
+
+func TestAnimation_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		anim  Animation
+		valid bool
+	}{
+		{"no keyframe", Animation{}, false},
+		{"negative duration", Animation{Keyframes: []Keyframe{{Color: ColorRed, Duration: -1}}, Loops: 1}, false},
+		{"infinite without duration", Animation{Keyframes: []Keyframe{{Color: ColorRed}}}, false},
+		{"fade", Fade(ColorRed, time.Second, nil), true},
+		{"breathe", Breathe(ColorRed, time.Second), true},
+		{"cycle", ColorCycle(time.Second, ColorRed, ColorGreen), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.anim.validate()
+			if (err == nil) != c.valid {
+				t.Errorf("validate(): %v, wants valid=%v", err, c.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package led
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi"
+	"sync"
+)
+
+const (
+	apa102Frequency = 4 * physic.MegaHertz
+
+	// MaxAPA102Brightness is the max value of 5 bits global brightness field
+	MaxAPA102Brightness = 31
+)
+
+// APA102 drives a strip of APA102/DotStar leds over spi.
+//
+// Frame is composed of a 32 bits start frame of 0, 4 bytes per pixel (0b111 + 5 bits
+// brightness, blue, green, red) and an end frame of at least half a bit per pixel.
+type APA102 struct {
+	conn spi.Conn
+
+	muBrightness sync.RWMutex
+	brightness   uint8
+
+	muTx sync.Mutex
+	buf  []byte
+
+	*strip
+}
+
+func NewAPA102(p spi.Port, pixelCount int, opts ...StripOption) (*APA102, error) {
+	if pixelCount <= 0 {
+		return nil, fmt.Errorf("invalid pixel count %v", pixelCount)
+	}
+	c, err := p.Connect(apa102Frequency, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to spi port: %v", err)
+	}
+
+	s := APA102{
+		conn:       c,
+		brightness: MaxAPA102Brightness,
+		buf:        make([]byte, apa102FrameSize(pixelCount)),
+	}
+	s.strip = newStrip(pixelCount, s.write, opts...)
+	s.off()
+	return &s, nil
+}
+
+// SetBrightness configures global brightness field (0-31) sent with each pixel
+func (s *APA102) SetBrightness(brightness uint8) error {
+	if brightness > MaxAPA102Brightness {
+		return fmt.Errorf("invalid brightness %v, must be in range [0, %v]", brightness, MaxAPA102Brightness)
+	}
+	s.muBrightness.Lock()
+	s.brightness = brightness
+	s.muBrightness.Unlock()
+
+	s.on()
+	return nil
+}
+
+func (s *APA102) Brightness() uint8 {
+	s.muBrightness.RLock()
+	defer s.muBrightness.RUnlock()
+	return s.brightness
+}
+
+func (s *APA102) write(pixels []Color) {
+	s.muTx.Lock()
+	defer s.muTx.Unlock()
+
+	encodeAPA102(pixels, s.Brightness(), s.buf)
+	if err := s.conn.Tx(s.buf, nil); err != nil {
+		zap.S().Errorf("unable to write apa102 pixels: %v", err)
+	}
+}
+
+func apa102FrameSize(pixelCount int) int {
+	return 4 + pixelCount*4 + apa102EndFrameSize(pixelCount)
+}
+
+func apa102EndFrameSize(pixelCount int) int {
+	size := (pixelCount + 15) / 16
+	if size < 4 {
+		return 4
+	}
+	return size
+}
+
+// encodeAPA102 writes start frame, pixels and end frame to dst
+func encodeAPA102(pixels []Color, brightness uint8, dst []byte) {
+	for i := 0; i < 4; i++ {
+		dst[i] = 0
+	}
+	for i, p := range pixels {
+		offset := 4 + i*4
+		dst[offset] = 0xE0 | (brightness & MaxAPA102Brightness)
+		dst[offset+1] = clampChannel(p.Blue)
+		dst[offset+2] = clampChannel(p.Green)
+		dst[offset+3] = clampChannel(p.Red)
+	}
+	for i := 4 + len(pixels)*4; i < len(dst); i++ {
+		dst[i] = 0xFF
+	}
+}
@@ -0,0 +1,90 @@
+package led
+
+import (
+	"bytes"
+	"periph.io/x/conn/v3/spi/spitest"
+	"testing"
+)
+
+func TestAPA102_SetColor(t *testing.T) {
+	port := spitest.Record{}
+	s, err := NewAPA102(&port, 2)
+	if err != nil {
+		t.Fatalf("unable to init apa102: %v", err)
+	}
+
+	s.SetColor(ColorTurquoise)
+	if s.Color() != ColorTurquoise {
+		t.Errorf("%T.Color(): %v, wants %v", s, s.Color(), ColorTurquoise)
+	}
+
+	if len(port.Ops) != 2 {
+		t.Fatalf("%v spi writes, wants %v", len(port.Ops), 2)
+	}
+	expectedOff := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0xFF, 0x00, 0x00, 0x00,
+		0xFF, 0x00, 0x00, 0x00,
+		0xFF, 0xFF, 0xFF, 0xFF,
+	}
+	if !bytes.Equal(port.Ops[0].W, expectedOff) {
+		t.Errorf("init frame: %#v, wants %#v", port.Ops[0].W, expectedOff)
+	}
+	// brightness + BGR order
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0xFF, 208, 224, 64,
+		0xFF, 208, 224, 64,
+		0xFF, 0xFF, 0xFF, 0xFF,
+	}
+	if !bytes.Equal(port.Ops[1].W, expected) {
+		t.Errorf("frame: %#v, wants %#v", port.Ops[1].W, expected)
+	}
+}
+
+func TestAPA102_SetBrightness(t *testing.T) {
+	port := spitest.Record{}
+	s, err := NewAPA102(&port, 1)
+	if err != nil {
+		t.Fatalf("unable to init apa102: %v", err)
+	}
+	if err := s.SetPixel(0, ColorRed); err != nil {
+		t.Errorf("unable to set pixel: %v", err)
+	}
+
+	if err := s.SetBrightness(5); err != nil {
+		t.Errorf("unable to set brightness: %v", err)
+	}
+	if s.Brightness() != 5 {
+		t.Errorf("%T.Brightness(): %v, wants %v", s, s.Brightness(), 5)
+	}
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0xE5, 0x00, 0x00, 0xFF,
+		0xFF, 0xFF, 0xFF, 0xFF,
+	}
+	if last := port.Ops[len(port.Ops)-1].W; !bytes.Equal(last, expected) {
+		t.Errorf("frame: %#v, wants %#v", last, expected)
+	}
+
+	if err := s.SetBrightness(MaxAPA102Brightness + 1); err == nil {
+		t.Errorf("SetBrightness must fail with out of range value")
+	}
+}
+
+func TestAPA102EndFrameSize(t *testing.T) {
+	cases := []struct {
+		pixels   int
+		expected int
+	}{
+		{1, 4},
+		{64, 4},
+		{65, 5},
+		{100, 7},
+	}
+	for _, c := range cases {
+		if size := apa102EndFrameSize(c.pixels); size != c.expected {
+			t.Errorf("apa102EndFrameSize(%v): %v, wants %v", c.pixels, size, c.expected)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package led
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BlinkPattern describes led blink rhythm: led is lit during On then switched off during Off.
+// When Count > 0, pulses are grouped by bursts of Count pulses separated by an additional Pause.
+type BlinkPattern struct {
+	On    time.Duration
+	Off   time.Duration
+	Count int
+	Pause time.Duration
+}
+
+// Enabled returns false if pattern doesn't blink (led steady on)
+func (p BlinkPattern) Enabled() bool {
+	return p.On > 0 && p.Off > 0
+}
+
+// BlinkFrequency returns a continuous pattern where each on/off phase lasts 1/freq second
+func BlinkFrequency(freq float64) BlinkPattern {
+	if freq <= 0 {
+		return BlinkPattern{}
+	}
+	d := time.Duration(float64(time.Second) / freq)
+	return BlinkPattern{On: d, Off: d}
+}
+
+// ParseBlinkPattern reads a blink pattern written as a frequency ("2hz"), as on,off durations
+// ("100ms,900ms") or as on,off,count,pause ("100ms,100ms,3,1s"). Empty string and "off" disable blink.
+func ParseBlinkPattern(s string) (BlinkPattern, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "off" {
+		return BlinkPattern{}, nil
+	}
+	if freq, ok := strings.CutSuffix(s, "hz"); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(freq), 64)
+		if err != nil || f <= 0 {
+			return BlinkPattern{}, fmt.Errorf("invalid blink frequency '%v'", s)
+		}
+		return BlinkFrequency(f), nil
+	}
+
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 && len(fields) != 4 {
+		return BlinkPattern{}, fmt.Errorf("invalid blink pattern '%v', must be freq hz, on,off or on,off,count,pause", s)
+	}
+	var p BlinkPattern
+	var err error
+	if p.On, err = time.ParseDuration(strings.TrimSpace(fields[0])); err != nil {
+		return BlinkPattern{}, fmt.Errorf("invalid on duration of blink pattern '%v': %v", s, err)
+	}
+	if p.Off, err = time.ParseDuration(strings.TrimSpace(fields[1])); err != nil {
+		return BlinkPattern{}, fmt.Errorf("invalid off duration of blink pattern '%v': %v", s, err)
+	}
+	if len(fields) == 4 {
+		if p.Count, err = strconv.Atoi(strings.TrimSpace(fields[2])); err != nil || p.Count < 0 {
+			return BlinkPattern{}, fmt.Errorf("invalid count of blink pattern '%v'", s)
+		}
+		if p.Pause, err = time.ParseDuration(strings.TrimSpace(fields[3])); err != nil {
+			return BlinkPattern{}, fmt.Errorf("invalid pause of blink pattern '%v': %v", s, err)
+		}
+	}
+	if !p.Enabled() {
+		return BlinkPattern{}, fmt.Errorf("invalid blink pattern '%v', on and off durations must be > 0", s)
+	}
+	return p, nil
+}
+
+// String formats pattern in a form read by ParseBlinkPattern
+func (p BlinkPattern) String() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	if p.Count > 0 {
+		return fmt.Sprintf("%v,%v,%v,%v", p.On, p.Off, p.Count, p.Pause)
+	}
+	return fmt.Sprintf("%v,%v", p.On, p.Off)
+}
+
+func (p BlinkPattern) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *BlinkPattern) UnmarshalText(text []byte) error {
+	pattern, err := ParseBlinkPattern(string(text))
+	if err != nil {
+		return err
+	}
+	*p = pattern
+	return nil
+}
+
+// blinker switches a led off and on from a goroutine according a BlinkPattern
+type blinker struct {
+	on, off func()
+	clock   clock.Clock
+
+	muBlink sync.Mutex
+	pattern BlinkPattern
+	running bool
+	// lit is false during dark phase of blink
+	lit bool
+	// update notifies running goroutine that pattern changed
+	update chan struct{}
+	// done is closed when goroutine stops
+	done chan struct{}
+}
+
+func newBlinker(on, off func(), clk clock.Clock) *blinker {
+	return &blinker{
+		on:     on,
+		off:    off,
+		clock:  clk,
+		update: make(chan struct{}, 1),
+		lit:    true,
+	}
+}
+
+// show displays current color, except during dark phase of blink: color is then displayed at next lit
+// phase
+func (b *blinker) show() {
+	b.muBlink.Lock()
+	defer b.muBlink.Unlock()
+	if b.lit {
+		b.on()
+	}
+}
+
+// switchOn lights led, or switches it off, and records blink phase
+func (b *blinker) switchOn(lit bool) {
+	b.muBlink.Lock()
+	defer b.muBlink.Unlock()
+	b.lit = lit
+	if lit {
+		b.on()
+	} else {
+		b.off()
+	}
+}
+
+func (b *blinker) SetBlink(freq float64) {
+	b.SetBlinkPattern(BlinkFrequency(freq))
+}
+
+// SetBlinkPattern applies pattern immediately, it doesn't wait for blink goroutine
+func (b *blinker) SetBlinkPattern(pattern BlinkPattern) {
+	b.muBlink.Lock()
+	defer b.muBlink.Unlock()
+	if pattern == b.pattern {
+		return
+	}
+	b.pattern = pattern
+
+	if b.running {
+		select {
+		case b.update <- struct{}{}:
+		default:
+			// goroutine already notified
+		}
+		return
+	}
+	if pattern.Enabled() {
+		b.running = true
+		b.done = make(chan struct{})
+		go b.blink(pattern, b.done)
+	}
+}
+
+// wait blocks until last started goroutine stops, once blink is disabled
+func (b *blinker) wait() {
+	b.muBlink.Lock()
+	done := b.done
+	b.muBlink.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func (b *blinker) blink(pattern BlinkPattern, done chan<- struct{}) {
+	defer close(done)
+	log := zap.S().With("func", "blink")
+
+	timer := b.clock.NewTimer(pattern.On)
+	defer timer.Stop()
+
+	lit := true
+	pulse := 0
+	for {
+		select {
+		case <-timer.C():
+		case <-b.update:
+			if !timer.Stop() {
+				select {
+				case <-timer.C():
+				default:
+				}
+			}
+
+			b.muBlink.Lock()
+			pattern = b.pattern
+			if !pattern.Enabled() {
+				b.running = false
+				// Restore values
+				b.lit = true
+				b.on()
+				b.muBlink.Unlock()
+				return
+			}
+			b.muBlink.Unlock()
+
+			log.Debugf("restart with pattern %v", pattern)
+			b.switchOn(true)
+			lit = true
+			pulse = 0
+			timer.Reset(pattern.On)
+			continue
+		}
+
+		if lit {
+			log.Debug("off")
+			b.switchOn(false)
+			lit = false
+			pulse += 1
+			wait := pattern.Off
+			if pattern.Count > 0 && pulse >= pattern.Count {
+				wait += pattern.Pause
+				pulse = 0
+			}
+			timer.Reset(wait)
+		} else {
+			log.Debug("on")
+			b.switchOn(true)
+			lit = true
+			timer.Reset(pattern.On)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package led
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeBlinkTarget struct {
+	mu       sync.Mutex
+	lit      bool
+	switches []time.Time
+	// onCalls is notified on on() calls if not nil, notifications are dropped when buffer is full
+	onCalls chan struct{}
+}
+
+func (f *fakeBlinkTarget) on() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lit = true
+	select {
+	case f.onCalls <- struct{}{}:
+	default:
+	}
+}
+
+func (f *fakeBlinkTarget) off() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lit = false
+	f.switches = append(f.switches, time.Now())
+}
+
+func (f *fakeBlinkTarget) state() (bool, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lit, len(f.switches)
+}
+
+func TestBlinker_SetBlinkPatternNeverBlocks(t *testing.T) {
+	target := fakeBlinkTarget{}
+	b := newBlinker(target.on, target.off, clock.NewFake(time.Now()))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			b.SetBlink(float64(i%3) * 100)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("SetBlink calls blocked")
+	}
+
+	b.SetBlink(0)
+	b.wait()
+	if lit, _ := target.state(); !lit {
+		t.Errorf("led must be lit after blink stop")
+	}
+}
+
+func TestBlinker_ChangeFrequencyWhileBlinking(t *testing.T) {
+	target := fakeBlinkTarget{onCalls: make(chan struct{}, 1)}
+	clk := clock.NewFake(time.Now())
+	b := newBlinker(target.on, target.off, clk)
+
+	b.SetBlink(2)
+	clk.BlockUntil(1)
+	clk.Advance(20 * time.Millisecond)
+	if _, switches := target.state(); switches != 0 {
+		t.Errorf("led switched off %v times, wants %v", switches, 0)
+	}
+
+	// New frequency must be applied without waiting end of current phase (500ms)
+	start := clk.Now()
+	b.SetBlink(100)
+	// Goroutine lights led and arms timer with new pattern
+	select {
+	case <-target.onCalls:
+	case <-time.After(time.Second):
+		t.Fatalf("blink not restarted with new frequency")
+	}
+	clk.BlockUntil(1)
+	clk.Advance(10 * time.Millisecond)
+	clk.BlockUntil(1)
+	if _, switches := target.state(); switches != 1 {
+		t.Errorf("led switched off %v times with new frequency, wants %v", switches, 1)
+	}
+	if elapsed := clk.Now().Sub(start); elapsed > 100*time.Millisecond {
+		t.Errorf("led switched off after %v with new frequency, wants less than %v", elapsed, 100*time.Millisecond)
+	}
+	b.SetBlink(0)
+}
+
+func TestBlinker_Burst(t *testing.T) {
+	target := fakeBlinkTarget{}
+	clk := clock.NewFake(time.Now())
+	b := newBlinker(target.on, target.off, clk)
+	advance := func(d time.Duration) {
+		clk.Advance(d)
+		clk.BlockUntil(1)
+	}
+
+	b.SetBlinkPattern(BlinkPattern{On: 5 * time.Millisecond, Off: 5 * time.Millisecond, Count: 2, Pause: 40 * time.Millisecond})
+	clk.BlockUntil(1)
+	for i := 0; i < 4; i++ {
+		advance(5 * time.Millisecond)
+	}
+	if _, switches := target.state(); switches != 2 {
+		t.Errorf("led switched off %v times during first burst, wants %v", switches, 2)
+	}
+
+	// Pause between bursts
+	advance(40 * time.Millisecond)
+	if _, switches := target.state(); switches != 2 {
+		t.Errorf("led switched off %v times during pause, wants %v", switches, 2)
+	}
+
+	for i := 0; i < 4; i++ {
+		advance(5 * time.Millisecond)
+	}
+	if _, switches := target.state(); switches != 4 {
+		t.Errorf("led switched off %v times after second burst, wants %v", switches, 4)
+	}
+	b.SetBlinkPattern(BlinkPattern{})
+}
+
+func TestBlinkFrequency(t *testing.T) {
+	p := BlinkFrequency(4)
+	if p.On != 250*time.Millisecond || p.Off != 250*time.Millisecond || p.Count != 0 {
+		t.Errorf("BlinkFrequency(4): %v, wants 250ms on/off", p)
+	}
+	if BlinkFrequency(0).Enabled() {
+		t.Errorf("BlinkFrequency(0) must be disabled")
+	}
+}
+
+func TestParseBlinkPattern(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected BlinkPattern
+	}{
+		{"", BlinkPattern{}},
+		{"off", BlinkPattern{}},
+		{"4Hz", BlinkFrequency(4)},
+		{"100ms, 900ms", BlinkPattern{On: 100 * time.Millisecond, Off: 900 * time.Millisecond}},
+		{"100ms,100ms,3,1s", BlinkPattern{On: 100 * time.Millisecond, Off: 100 * time.Millisecond, Count: 3, Pause: time.Second}},
+	}
+	for _, c := range cases {
+		p, err := ParseBlinkPattern(c.value)
+		if err != nil {
+			t.Errorf("ParseBlinkPattern(%q): unexpected error %v", c.value, err)
+			continue
+		}
+		if p != c.expected {
+			t.Errorf("ParseBlinkPattern(%q): %v, wants %v", c.value, p, c.expected)
+		}
+		if back, _ := ParseBlinkPattern(p.String()); back != p {
+			t.Errorf("ParseBlinkPattern(%q) doesn't read back String(): %v", p.String(), back)
+		}
+	}
+
+	for _, invalid := range []string{"0hz", "fast", "100ms", "0s,1s", "1s,1s,-1,1s"} {
+		if _, err := ParseBlinkPattern(invalid); err == nil {
+			t.Errorf("ParseBlinkPattern(%q): no error", invalid)
+		}
+	}
+}
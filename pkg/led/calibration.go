@@ -0,0 +1,110 @@
+package led
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+)
+
+// Calibration corrects colors just before output so that they look right on real leds: Gamma
+// compensates non-linear perception of low intensities and Red, Green, Blue scale each channel
+// (white balance)
+type Calibration struct {
+	Gamma float64 `json:"gamma"`
+	Red   float64 `json:"red"`
+	Green float64 `json:"green"`
+	Blue  float64 `json:"blue"`
+}
+
+// DefaultCalibration outputs colors unchanged
+var DefaultCalibration = Calibration{Gamma: 1, Red: 1, Green: 1, Blue: 1}
+
+func (c Calibration) Validate() error {
+	if c.Gamma <= 0 {
+		return fmt.Errorf("invalid gamma %v, must be > 0", c.Gamma)
+	}
+	for _, s := range []struct {
+		name  string
+		value float64
+	}{{"red", c.Red}, {"green", c.Green}, {"blue", c.Blue}} {
+		if s.value < 0 || s.value > 1 {
+			return fmt.Errorf("invalid %v scale %v, must be in range [0, 1]", s.name, s.value)
+		}
+	}
+	return nil
+}
+
+// Apply returns color to output for logical color
+func (c Calibration) Apply(color Color) Color {
+	return Color{
+		Red:   c.channel(color.Red, c.Red),
+		Green: c.channel(color.Green, c.Green),
+		Blue:  c.channel(color.Blue, c.Blue),
+	}
+}
+
+func (c Calibration) channel(v int, scale float64) int {
+	return int(math.Round(255 * scale * math.Pow(float64(clampChannel(v))/255, c.Gamma)))
+}
+
+// LoadCalibration reads calibration from a json file ({"gamma": 2.2, "red": 1, "green": 0.6, "blue": 0.8}),
+// missing fields keep default value
+func LoadCalibration(path string) (Calibration, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Calibration{}, fmt.Errorf("unable to read calibration file: %v", err)
+	}
+	c := DefaultCalibration
+	if err := json.Unmarshal(content, &c); err != nil {
+		return Calibration{}, fmt.Errorf("unable to parse calibration file '%v': %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return Calibration{}, fmt.Errorf("invalid calibration file '%v': %v", path, err)
+	}
+	return c, nil
+}
+
+// Calibrated is implemented by leds that correct colors before output
+type Calibrated interface {
+	SetCalibration(c Calibration) error
+}
+
+// calibrator holds calibration shared by led backends, refresh renders current color again
+type calibrator struct {
+	muCalibration sync.RWMutex
+	calibration   Calibration
+
+	refresh func()
+}
+
+func newCalibrator(refresh func()) *calibrator {
+	return &calibrator{
+		calibration: DefaultCalibration,
+		refresh:     refresh,
+	}
+}
+
+// SetCalibration applies c to next outputs and renders current color again
+func (c *calibrator) SetCalibration(calibration Calibration) error {
+	if err := calibration.Validate(); err != nil {
+		return err
+	}
+	c.muCalibration.Lock()
+	c.calibration = calibration
+	c.muCalibration.Unlock()
+
+	c.refresh()
+	return nil
+}
+
+func (c *calibrator) Calibration() Calibration {
+	c.muCalibration.RLock()
+	defer c.muCalibration.RUnlock()
+	return c.calibration
+}
+
+func (c *calibrator) correct(color Color) Color {
+	return c.Calibration().Apply(color)
+}
@@ -0,0 +1,144 @@
+package led
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi/spitest"
+	"sync"
+	"testing"
+)
+
+func TestCalibration_Apply(t *testing.T) {
+	for v := 0; v <= 255; v++ {
+		c := Color{v, v, v}
+		if o := DefaultCalibration.Apply(c); o != c {
+			t.Errorf("default calibration changes %v to %v", c, o)
+		}
+	}
+
+	cases := []struct {
+		name        string
+		calibration Calibration
+		color       Color
+		expected    Color
+	}{
+		{"gamma", Calibration{Gamma: 2.2, Red: 1, Green: 1, Blue: 1}, Color{128, 255, 0}, Color{56, 255, 0}},
+		{"white balance", Calibration{Gamma: 1, Red: 1, Green: 0.5, Blue: 0.8}, ColorWhite, Color{255, 128, 204}},
+		{"gamma and white balance", Calibration{Gamma: 2.2, Red: 1, Green: 0.5, Blue: 1}, Color{128, 128, 128}, Color{56, 28, 56}},
+		{"out of range", DefaultCalibration, Color{-10, 300, 0}, Color{0, 255, 0}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if o := c.calibration.Apply(c.color); o != c.expected {
+				t.Errorf("Apply(%v): %v, wants %v", c.color, o, c.expected)
+			}
+		})
+	}
+}
+
+func TestCalibration_Validate(t *testing.T) {
+	cases := []struct {
+		name        string
+		calibration Calibration
+		valid       bool
+	}{
+		{"default", DefaultCalibration, true},
+		{"null gamma", Calibration{Gamma: 0, Red: 1, Green: 1, Blue: 1}, false},
+		{"negative scale", Calibration{Gamma: 1, Red: -0.1, Green: 1, Blue: 1}, false},
+		{"scale too high", Calibration{Gamma: 1, Red: 1, Green: 1.5, Blue: 1}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.calibration.Validate()
+			if (err == nil) != c.valid {
+				t.Errorf("Validate(): %v, wants valid=%v", err, c.valid)
+			}
+		})
+	}
+}
+
+func TestLoadCalibration(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+		return path
+	}
+
+	c, err := LoadCalibration(write("partial.json", `{"gamma": 2.2, "green": 0.6}`))
+	if err != nil {
+		t.Fatalf("unable to load calibration: %v", err)
+	}
+	expected := Calibration{Gamma: 2.2, Red: 1, Green: 0.6, Blue: 1}
+	if c != expected {
+		t.Errorf("LoadCalibration(): %v, wants %v", c, expected)
+	}
+
+	if _, err := LoadCalibration(write("invalid.json", `{"gamma": -1}`)); err == nil {
+		t.Errorf("invalid calibration must fail")
+	}
+	if _, err := LoadCalibration(write("malformed.json", `gamma: 2.2`)); err == nil {
+		t.Errorf("malformed file must fail")
+	}
+	if _, err := LoadCalibration(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("missing file must fail")
+	}
+}
+
+func TestColorLed_SetCalibration(t *testing.T) {
+	setLedBackup := setLed
+	defer func() { setLed = setLedBackup }()
+
+	l := New()
+	var muFakeValue sync.Mutex
+	values := make(map[gpio.PinIO]int)
+	setLed = func(v int, led gpio.PinIO, _ physic.Frequency, mutex *sync.Mutex) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		muFakeValue.Lock()
+		defer muFakeValue.Unlock()
+		values[led] = v
+	}
+
+	l.SetColor(ColorAqua)
+	if err := l.SetCalibration(Calibration{Gamma: 1, Red: 1, Green: 0.5, Blue: 1}); err != nil {
+		t.Fatalf("unable to set calibration: %v", err)
+	}
+	if values[l.pinGreen] != 128 || values[l.pinBlue] != 255 {
+		t.Errorf("output after calibration: green=%v, blue=%v, wants 128, 255", values[l.pinGreen], values[l.pinBlue])
+	}
+	if l.Color() != ColorAqua {
+		t.Errorf("%T.Color(): %v, wants logical color %v", l, l.Color(), ColorAqua)
+	}
+
+	if err := l.SetCalibration(Calibration{}); err == nil {
+		t.Errorf("invalid calibration must be rejected")
+	}
+}
+
+func TestWS2812_SetCalibration(t *testing.T) {
+	port := spitest.Record{}
+	s, err := NewWS2812(&port, 1)
+	if err != nil {
+		t.Fatalf("unable to init ws2812: %v", err)
+	}
+	if err := s.SetCalibration(Calibration{Gamma: 1, Red: 1, Green: 0.5, Blue: 1}); err != nil {
+		t.Fatalf("unable to set calibration: %v", err)
+	}
+	s.SetColor(ColorGreen)
+
+	half := make([]byte, 3)
+	encodeWS2812Byte(128, half)
+	expected := ws2812Frame(half, ws2812Zero, ws2812Zero)
+	if w := port.Ops[len(port.Ops)-1].W; !bytes.Equal(w, expected) {
+		t.Errorf("frame: %#v, wants %#v", w, expected)
+	}
+	if s.Color() != ColorGreen {
+		t.Errorf("%T.Color(): %v, wants logical color %v", s, s.Color(), ColorGreen)
+	}
+}
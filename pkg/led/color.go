@@ -0,0 +1,197 @@
+package led
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// colorNames lists colors accepted by ParseColor
+var colorNames = map[string]Color{
+	"black":     ColorBlack,
+	"red":       ColorRed,
+	"purple":    ColorPurple,
+	"yellow":    ColorYellow,
+	"green":     ColorGreen,
+	"turquoise": ColorTurquoise,
+	"aqua":      ColorAqua,
+	"blue":      ColorBlue,
+	"white":     ColorWhite,
+}
+
+// ParseColor reads a color written as #RRGGBB, rgb(r, g, b) or a color name (red, aqua, ...)
+func ParseColor(s string) (Color, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+
+	if strings.HasPrefix(value, "#") {
+		hex := value[1:]
+		if len(hex) != 6 {
+			return Color{}, fmt.Errorf("invalid color '%v', hex value must be #RRGGBB", s)
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return Color{}, fmt.Errorf("invalid color '%v': %v", s, err)
+		}
+		return Color{Red: int(v >> 16 & 0xFF), Green: int(v >> 8 & 0xFF), Blue: int(v & 0xFF)}, nil
+	}
+
+	if strings.HasPrefix(value, "rgb(") && strings.HasSuffix(value, ")") {
+		fields := strings.Split(value[len("rgb("):len(value)-1], ",")
+		if len(fields) != 3 {
+			return Color{}, fmt.Errorf("invalid color '%v', rgb value must have 3 channels", s)
+		}
+		var channels [3]int
+		for i, f := range fields {
+			v, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return Color{}, fmt.Errorf("invalid color '%v': %v", s, err)
+			}
+			if v < 0 || v > 255 {
+				return Color{}, fmt.Errorf("invalid color '%v', channel value %v out of range [0, 255]", s, v)
+			}
+			channels[i] = v
+		}
+		return Color{Red: channels[0], Green: channels[1], Blue: channels[2]}, nil
+	}
+
+	if c, ok := colorNames[value]; ok {
+		return c, nil
+	}
+	return Color{}, fmt.Errorf("unknown color '%v'", s)
+}
+
+// String formats color as #RRGGBB, channels are clamped
+func (c Color) String() string {
+	c = c.Clamp()
+	return fmt.Sprintf("#%02X%02X%02X", c.Red, c.Green, c.Blue)
+}
+
+// MarshalText formats color as #RRGGBB, it allows to write colors in config files
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText reads any color format supported by ParseColor
+func (c *Color) UnmarshalText(text []byte) error {
+	v, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// Clamp limits each channel to range [0, 255]
+func (c Color) Clamp() Color {
+	return Color{
+		Red:   int(clampChannel(c.Red)),
+		Green: int(clampChannel(c.Green)),
+		Blue:  int(clampChannel(c.Blue)),
+	}
+}
+
+// Scale multiplies each channel by f, result isn't clamped
+func (c Color) Scale(f float64) Color {
+	return Color{
+		Red:   int(math.Round(float64(c.Red) * f)),
+		Green: int(math.Round(float64(c.Green) * f)),
+		Blue:  int(math.Round(float64(c.Blue) * f)),
+	}
+}
+
+// Lerp interpolates linearly from c1 (t=0) to c2 (t=1)
+func Lerp(c1, c2 Color, t float64) Color {
+	lerp := func(v1, v2 int) int {
+		return v1 + int(math.Round(float64(v2-v1)*t))
+	}
+	return Color{
+		Red:   lerp(c1.Red, c2.Red),
+		Green: lerp(c1.Green, c2.Green),
+		Blue:  lerp(c1.Blue, c2.Blue),
+	}
+}
+
+// HSV builds color from hue (degrees), saturation and value in range [0, 1]
+func HSV(h, s, v float64) Color {
+	c := v * s
+	return hueToColor(h, c, v-c)
+}
+
+// HSV returns hue (degrees in range [0, 360)), saturation and value of color
+func (c Color) HSV() (h, s, v float64) {
+	h, max, min := c.hue()
+	if max > 0 {
+		s = (max - min) / max
+	}
+	return h, s, max
+}
+
+// HSL builds color from hue (degrees), saturation and lightness in range [0, 1]
+func HSL(h, s, l float64) Color {
+	c := (1 - math.Abs(2*l-1)) * s
+	return hueToColor(h, c, l-c/2)
+}
+
+// HSL returns hue (degrees in range [0, 360)), saturation and lightness of color
+func (c Color) HSL() (h, s, l float64) {
+	h, max, min := c.hue()
+	l = (max + min) / 2
+	if max != min {
+		s = (max - min) / (1 - math.Abs(2*l-1))
+	}
+	return h, s, l
+}
+
+// hue returns hue of color with its max and min channels in range [0, 1]
+func (c Color) hue() (h, max, min float64) {
+	c = c.Clamp()
+	r, g, b := float64(c.Red)/255, float64(c.Green)/255, float64(c.Blue)/255
+	max = math.Max(r, math.Max(g, b))
+	min = math.Min(r, math.Min(g, b))
+	delta := max - min
+
+	switch {
+	case delta == 0:
+		h = 0
+	case max == r:
+		h = 60 * math.Mod((g-b)/delta, 6)
+	case max == g:
+		h = 60 * ((b-r)/delta + 2)
+	default:
+		h = 60 * ((r-g)/delta + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	return h, max, min
+}
+
+// hueToColor converts hue with chroma c to color, m is added to each channel
+func hueToColor(h, c, m float64) Color {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	channel := func(v float64) int {
+		return int(math.Round((v + m) * 255))
+	}
+	return Color{Red: channel(r), Green: channel(g), Blue: channel(b)}.Clamp()
+}
@@ -0,0 +1,154 @@
+package led
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected Color
+		valid    bool
+	}{
+		{"#40E0D0", ColorTurquoise, true},
+		{"#ff00ff", ColorPurple, true},
+		{"rgb(64, 224, 208)", ColorTurquoise, true},
+		{" RGB(0,0,255) ", ColorBlue, true},
+		{"Aqua", ColorAqua, true},
+		{"#FFF", Color{}, false},
+		{"#GG0000", Color{}, false},
+		{"rgb(0, 0)", Color{}, false},
+		{"rgb(0, 0, 256)", Color{}, false},
+		{"rgb(0, x, 0)", Color{}, false},
+		{"pink", Color{}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			color, err := ParseColor(c.value)
+			if (err == nil) != c.valid {
+				t.Fatalf("ParseColor(%q): %v, wants valid=%v", c.value, err, c.valid)
+			}
+			if color != c.expected {
+				t.Errorf("ParseColor(%q): %v, wants %v", c.value, color, c.expected)
+			}
+		})
+	}
+}
+
+func TestColor_String(t *testing.T) {
+	if s := ColorTurquoise.String(); s != "#40E0D0" {
+		t.Errorf("String(): %v, wants %v", s, "#40E0D0")
+	}
+	if s := fmt.Sprintf("%v", Color{-1, 300, 16}); s != "#00FF10" {
+		t.Errorf("String() out of range: %v, wants %v", s, "#00FF10")
+	}
+	c, err := ParseColor(ColorTurquoise.String())
+	if err != nil || c != ColorTurquoise {
+		t.Errorf("String() can't be parsed: %v, %v", c, err)
+	}
+}
+
+func TestColor_Text(t *testing.T) {
+	var c Color
+	if err := c.UnmarshalText([]byte("rgb(64, 224, 208)")); err != nil {
+		t.Fatalf("unable to unmarshal color: %v", err)
+	}
+	if c != ColorTurquoise {
+		t.Errorf("UnmarshalText(): %v, wants %v", c, ColorTurquoise)
+	}
+	if err := c.UnmarshalText([]byte("unknown")); err == nil {
+		t.Errorf("invalid color must fail")
+	}
+	text, err := ColorTurquoise.MarshalText()
+	if err != nil || string(text) != "#40E0D0" {
+		t.Errorf("MarshalText(): %s, %v", text, err)
+	}
+}
+
+func TestColor_ScaleClamp(t *testing.T) {
+	if c := ColorTurquoise.Scale(0.5); c != (Color{32, 112, 104}) {
+		t.Errorf("Scale(0.5): %v", c)
+	}
+	if c := ColorTurquoise.Scale(2); c != (Color{128, 448, 416}) {
+		t.Errorf("Scale(2): %v", c)
+	}
+	if c := (Color{-5, 448, 255}).Clamp(); c != (Color{0, 255, 255}) {
+		t.Errorf("Clamp(): %v", c)
+	}
+}
+
+func TestLerp(t *testing.T) {
+	cases := []struct {
+		from, to Color
+		t        float64
+		expected Color
+	}{
+		{ColorBlack, ColorWhite, 0, ColorBlack},
+		{ColorBlack, ColorWhite, 1, ColorWhite},
+		{ColorBlack, ColorWhite, 0.5, Color{128, 128, 128}},
+		{ColorRed, ColorBlue, 0.25, Color{191, 0, 64}},
+	}
+	for _, c := range cases {
+		if v := Lerp(c.from, c.to, c.t); v != c.expected {
+			t.Errorf("Lerp(%v, %v, %v): %v, wants %v", c.from, c.to, c.t, v, c.expected)
+		}
+	}
+}
+
+func TestHSV(t *testing.T) {
+	cases := []struct {
+		h, s, v float64
+		color   Color
+	}{
+		{0, 0, 0, ColorBlack},
+		{0, 0, 1, ColorWhite},
+		{0, 1, 1, ColorRed},
+		{60, 1, 1, ColorYellow},
+		{120, 1, 1, ColorGreen},
+		{180, 1, 1, ColorAqua},
+		{240, 1, 1, ColorBlue},
+		{300, 1, 1, ColorPurple},
+		{174, 0.714, 0.878, Color{64, 224, 208}},
+	}
+	for _, c := range cases {
+		if color := HSV(c.h, c.s, c.v); color != c.color {
+			t.Errorf("HSV(%v, %v, %v): %v, wants %v", c.h, c.s, c.v, color, c.color)
+		}
+		h, s, v := c.color.HSV()
+		if !almostEqual(h, c.h, 0.5) || !almostEqual(s, c.s, 0.005) || !almostEqual(v, c.v, 0.005) {
+			t.Errorf("%v.HSV(): (%v, %v, %v), wants (%v, %v, %v)", c.color, h, s, v, c.h, c.s, c.v)
+		}
+	}
+	if c := HSV(-120, 1, 1); c != ColorBlue {
+		t.Errorf("HSV with negative hue: %v, wants %v", c, ColorBlue)
+	}
+}
+
+func TestHSL(t *testing.T) {
+	cases := []struct {
+		h, s, l float64
+		color   Color
+	}{
+		{0, 0, 0, ColorBlack},
+		{0, 0, 1, ColorWhite},
+		{0, 1, 0.5, ColorRed},
+		{120, 1, 0.5, ColorGreen},
+		{240, 1, 0.5, ColorBlue},
+		{174, 0.721, 0.565, Color{64, 224, 208}},
+	}
+	for _, c := range cases {
+		if color := HSL(c.h, c.s, c.l); color != c.color {
+			t.Errorf("HSL(%v, %v, %v): %v, wants %v", c.h, c.s, c.l, color, c.color)
+		}
+		h, s, l := c.color.HSL()
+		if !almostEqual(h, c.h, 0.5) || !almostEqual(s, c.s, 0.005) || !almostEqual(l, c.l, 0.005) {
+			t.Errorf("%v.HSL(): (%v, %v, %v), wants (%v, %v, %v)", c.color, h, s, l, c.h, c.s, c.l)
+		}
+	}
+}
+
+func almostEqual(a, b, epsilon float64) bool {
+	return math.Abs(a-b) <= epsilon
+}
@@ -0,0 +1,70 @@
+package led
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Dimmer scales colors sent to a ColoredLed by a global brightness factor, brightness is kept
+// across color changes and blink
+type Dimmer struct {
+	led ColoredLed
+
+	mu           sync.RWMutex
+	brightness   float64
+	currentColor Color
+}
+
+func NewDimmer(l ColoredLed) *Dimmer {
+	return &Dimmer{
+		led:          l,
+		brightness:   1,
+		currentColor: ColorBlack,
+	}
+}
+
+// SetBrightness configures factor (0-1) applied to each channel and renders current color again
+func (d *Dimmer) SetBrightness(brightness float64) error {
+	if brightness < 0 || brightness > 1 || math.IsNaN(brightness) {
+		return fmt.Errorf("invalid brightness %v, must be in range [0, 1]", brightness)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.brightness = brightness
+	d.led.SetColor(d.dim(d.currentColor))
+	return nil
+}
+
+func (d *Dimmer) Brightness() float64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.brightness
+}
+
+func (d *Dimmer) SetColor(color Color) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.currentColor = color
+	d.led.SetColor(d.dim(color))
+}
+
+// Color returns color requested before dimming
+func (d *Dimmer) Color() Color {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.currentColor
+}
+
+func (d *Dimmer) SetBlink(freq float64) {
+	d.led.SetBlink(freq)
+}
+
+func (d *Dimmer) SetBlinkPattern(pattern BlinkPattern) {
+	d.led.SetBlinkPattern(pattern)
+}
+
+// dim must be called with mu locked
+func (d *Dimmer) dim(color Color) Color {
+	return color.Scale(d.brightness)
+}
@@ -0,0 +1,45 @@
+package led
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDimmer(t *testing.T) {
+	l := NewSimLed()
+	d := NewDimmer(l)
+
+	d.SetColor(ColorTurquoise)
+	if l.Color() != ColorTurquoise {
+		t.Errorf("color with full brightness: %v, wants %v", l.Color(), ColorTurquoise)
+	}
+
+	if err := d.SetBrightness(0.5); err != nil {
+		t.Fatalf("unable to set brightness: %v", err)
+	}
+	if expected := (Color{32, 112, 104}); l.Color() != expected {
+		t.Errorf("color after brightness change: %v, wants %v", l.Color(), expected)
+	}
+
+	d.SetColor(ColorWhite)
+	if expected := (Color{128, 128, 128}); l.Color() != expected {
+		t.Errorf("new color with brightness: %v, wants %v", l.Color(), expected)
+	}
+	if d.Color() != ColorWhite {
+		t.Errorf("%T.Color(): %v, wants %v", d, d.Color(), ColorWhite)
+	}
+
+	d.SetBlinkPattern(BlinkPattern{On: time.Second, Off: time.Second})
+	if l.Blink().On != time.Second {
+		t.Errorf("blink pattern not forwarded: %v", l.Blink())
+	}
+
+	for _, b := range []float64{-0.1, 1.1} {
+		if err := d.SetBrightness(b); err == nil {
+			t.Errorf("SetBrightness(%v) must fail", b)
+		}
+	}
+	if d.Brightness() != 0.5 {
+		t.Errorf("%T.Brightness(): %v, wants %v", d, d.Brightness(), 0.5)
+	}
+}
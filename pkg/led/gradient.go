@@ -0,0 +1,101 @@
+package led
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GradientStop fixes color of gradient at Position
+type GradientStop struct {
+	Position float64
+	Color    Color
+}
+
+// Gradient maps positions to colors interpolated between stops, sorted by position
+type Gradient []GradientStop
+
+func NewGradient(stops ...GradientStop) (Gradient, error) {
+	if len(stops) == 0 {
+		return nil, fmt.Errorf("gradient without stop")
+	}
+	g := make(Gradient, len(stops))
+	copy(g, stops)
+	sort.SliceStable(g, func(i, j int) bool {
+		return g[i].Position < g[j].Position
+	})
+	for i := 1; i < len(g); i++ {
+		if g[i].Position == g[i-1].Position {
+			return nil, fmt.Errorf("many gradient stops at position %v", g[i].Position)
+		}
+	}
+	return g, nil
+}
+
+// ParseGradient reads a comma separated list of position=color (ex: "-1=blue,0=black,1=#FF0000")
+func ParseGradient(s string) (Gradient, error) {
+	var stops []GradientStop
+	for _, item := range SplitList(s) {
+		position, color, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid gradient stop '%v', must be position=color", item)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(position), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid position of gradient stop '%v': %v", item, err)
+		}
+		c, err := ParseColor(color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color of gradient stop '%v': %v", item, err)
+		}
+		stops = append(stops, GradientStop{Position: p, Color: c})
+	}
+	return NewGradient(stops...)
+}
+
+// At returns color at position, positions out of gradient take color of nearest stop
+func (g Gradient) At(position float64) Color {
+	if len(g) == 0 {
+		return ColorBlack
+	}
+	if position <= g[0].Position {
+		return g[0].Color
+	}
+	for i := 1; i < len(g); i++ {
+		if position <= g[i].Position {
+			from, to := g[i-1], g[i]
+			return Lerp(from.Color, to.Color, (position-from.Position)/(to.Position-from.Position))
+		}
+	}
+	return g[len(g)-1].Color
+}
+
+func (g Gradient) String() string {
+	items := make([]string, len(g))
+	for i, s := range g {
+		items[i] = fmt.Sprintf("%v=%v", strconv.FormatFloat(s.Position, 'f', -1, 64), s.Color)
+	}
+	return strings.Join(items, ",")
+}
+
+// SplitList splits a comma separated list, commas between parentheses (rgb(r, g, b) colors) are kept
+func SplitList(s string) []string {
+	var result []string
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth += 1
+		case ')':
+			depth -= 1
+		case ',':
+			if depth == 0 {
+				result = append(result, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(result, s[start:])
+}
@@ -0,0 +1,64 @@
+package led
+
+import (
+	"testing"
+)
+
+func TestGradient_At(t *testing.T) {
+	g, err := NewGradient(
+		GradientStop{Position: 1, Color: ColorRed},
+		GradientStop{Position: -1, Color: ColorBlue},
+		GradientStop{Position: 0, Color: ColorBlack},
+	)
+	if err != nil {
+		t.Fatalf("unable to build gradient: %v", err)
+	}
+
+	cases := []struct {
+		position float64
+		expected Color
+	}{
+		{-2, ColorBlue},
+		{-1, ColorBlue},
+		{-0.5, Color{0, 0, 127}},
+		{0, ColorBlack},
+		{0.25, Color{64, 0, 0}},
+		{1, ColorRed},
+		{3, ColorRed},
+	}
+	for _, c := range cases {
+		if color := g.At(c.position); color != c.expected {
+			t.Errorf("At(%v): %v, wants %v", c.position, color, c.expected)
+		}
+	}
+
+	if _, err := NewGradient(); err == nil {
+		t.Errorf("gradient without stop must fail")
+	}
+	if _, err := NewGradient(GradientStop{0, ColorRed}, GradientStop{0, ColorBlue}); err == nil {
+		t.Errorf("gradient with duplicated positions must fail")
+	}
+}
+
+func TestParseGradient(t *testing.T) {
+	g, err := ParseGradient("-1=blue, 0=rgb(0, 0, 0),1=#FF0000")
+	if err != nil {
+		t.Fatalf("unable to parse gradient: %v", err)
+	}
+	if s := g.String(); s != "-1=#0000FF,0=#000000,1=#FF0000" {
+		t.Errorf("String(): %v", s)
+	}
+
+	for _, s := range []string{"", "blue", "x=blue", "0=pink"} {
+		if _, err := ParseGradient(s); err == nil {
+			t.Errorf("ParseGradient(%q) must fail", s)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	items := SplitList("a=rgb(1, 2, 3),b=red")
+	if len(items) != 2 || items[0] != "a=rgb(1, 2, 3)" || items[1] != "b=red" {
+		t.Errorf("SplitList(): %q", items)
+	}
+}
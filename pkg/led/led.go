@@ -1,21 +1,33 @@
 package led
 
 import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
 	"go.uber.org/zap"
 	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/conn/v3/physic"
 	"periph.io/x/host/v3"
 	"periph.io/x/host/v3/rpi"
 	"sync"
-	"time"
 )
 
-func init() {
-	zap.S().Info("init pin")
-	// Load all the drivers:
-	if _, err := host.Init(); err != nil {
-		zap.S().Fatalf("unable to init host driver: %v", err)
-	}
+var (
+	hostInit = host.Init
+	onceOpen sync.Once
+	errOpen  error
+)
 
+// Open loads host drivers needed by gpio and spi backends, it can be called many times and
+// returns result of first initialisation
+func Open() error {
+	onceOpen.Do(func() {
+		zap.S().Info("init host drivers")
+		if _, err := hostInit(); err != nil {
+			errOpen = fmt.Errorf("unable to init host driver: %v", err)
+		}
+	})
+	return errOpen
 }
 
 var (
@@ -30,19 +42,103 @@ var (
 	ColorWhite     = Color{255, 255, 255}
 )
 
-func New() *PiColorLed {
+type Option func(led *PiColorLed)
+
+// WithPWMFrequency configures frequency used to render channel intensities
+func WithPWMFrequency(f physic.Frequency) Option {
+	return func(led *PiColorLed) {
+		led.pwmFrequency = f
+	}
+}
+
+// WithPWMMode configures how pwm signal is generated on led pins
+func WithPWMMode(m PWMMode) Option {
+	return func(led *PiColorLed) {
+		led.pwmMode = m
+	}
+}
+
+// WithPolarity configures level that lights led channels
+func WithPolarity(p Polarity) Option {
+	return func(led *PiColorLed) {
+		led.polarity = p
+	}
+}
+
+// WithClock configures clock used by blink and software pwm
+func WithClock(c clock.Clock) Option {
+	return func(led *PiColorLed) {
+		led.clock = c
+	}
+}
+
+// WithPins configures gpio pins wired to each led channel
+func WithPins(red, green, blue gpio.PinIO) Option {
+	return func(led *PiColorLed) {
+		led.pinRed = red
+		led.pinGreen = green
+		led.pinBlue = blue
+	}
+}
+
+// New builds a led wired on gpio pins, Open must be called before to load drivers
+func New(opts ...Option) *PiColorLed {
 	led := PiColorLed{
-		pinRed:          rpi.P1_16,
-		pinGreen:        rpi.P1_18,
-		pinBlue:         rpi.P1_22,
-		currentColor:    ColorBlack,
-		cancelBlinkChan: make(chan interface{}),
-		blinkEnabled:    false,
+		pinRed:       rpi.P1_16,
+		pinGreen:     rpi.P1_18,
+		pinBlue:      rpi.P1_22,
+		pwmFrequency: DefaultPWMFrequency,
+		pwmMode:      PWMModeAuto,
+		polarity:     ActiveHigh,
+		clock:        clock.New(),
+		currentColor: ColorBlack,
 	}
 
+	for _, opt := range opts {
+		opt(&led)
+	}
+
+	led.blinker = newBlinker(led.on, led.off, led.clock)
+	led.calibrator = newCalibrator(led.show)
+
+	led.pinRed = newPWMPin(led.pinRed, led.pwmMode, led.polarity, led.clock)
+	led.pinGreen = newPWMPin(led.pinGreen, led.pwmMode, led.polarity, led.clock)
+	led.pinBlue = newPWMPin(led.pinBlue, led.pwmMode, led.polarity, led.clock)
+
+	// Ensure led is dark whatever the wiring
+	led.off()
+
 	return &led
 }
 
+// NewWithPinNames builds led with channel pins resolved from their gpio names (GPIO23, 23, ...)
+func NewWithPinNames(red, green, blue string, opts ...Option) (*PiColorLed, error) {
+	if err := Open(); err != nil {
+		return nil, err
+	}
+	pinRed, err := pinByName(red)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure red channel: %v", err)
+	}
+	pinGreen, err := pinByName(green)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure green channel: %v", err)
+	}
+	pinBlue, err := pinByName(blue)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure blue channel: %v", err)
+	}
+	return New(append(opts, WithPins(pinRed, pinGreen, pinBlue))...), nil
+}
+
+func pinByName(name string) (gpio.PinIO, error) {
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("no gpio pin found with name '%v'", name)
+	}
+	return p, nil
+}
+
 type Color struct {
 	Red   int
 	Green int
@@ -51,6 +147,7 @@ type Color struct {
 
 type Led interface {
 	SetBlink(freq float64)
+	SetBlinkPattern(pattern BlinkPattern)
 }
 
 type ColoredLed interface {
@@ -63,99 +160,59 @@ type PiColorLed struct {
 	pinRed                          gpio.PinIO
 	pinGreen                        gpio.PinIO
 	pinBlue                         gpio.PinIO
+	pwmFrequency                    physic.Frequency
+	pwmMode                         PWMMode
+	polarity                        Polarity
+	clock                           clock.Clock
 
 	muColorValue sync.RWMutex
 	currentColor Color
 
-	cancelBlinkChan chan interface{}
-
-	muBlink      sync.Mutex
-	blinkEnabled bool
+	*blinker
+	*calibrator
 }
 
 func (l *PiColorLed) SetColor(color Color) {
 	l.muColorValue.Lock()
-	defer l.muColorValue.Unlock()
 	if color == l.currentColor {
+		l.muColorValue.Unlock()
 		return
 	}
 	l.currentColor = color
-	setLed(color.Red, l.pinRed, &l.muPinRed)
-	setLed(color.Green, l.pinGreen, &l.muPinGreen)
-	setLed(color.Blue, l.pinBlue, &l.muPinBlue)
+	l.muColorValue.Unlock()
+	l.show()
 }
 
 func (l *PiColorLed) on() {
 	l.muColorValue.RLock()
 	defer l.muColorValue.RUnlock()
+	l.render(l.currentColor)
+}
 
-	setLed(l.currentColor.Red, l.pinRed, &l.muPinRed)
-	setLed(l.currentColor.Green, l.pinGreen, &l.muPinGreen)
-	setLed(l.currentColor.Blue, l.pinBlue, &l.muPinBlue)
+// render outputs calibrated color on pins
+func (l *PiColorLed) render(color Color) {
+	output := l.correct(color)
+	setLed(output.Red, l.pinRed, l.pwmFrequency, &l.muPinRed)
+	setLed(output.Green, l.pinGreen, l.pwmFrequency, &l.muPinGreen)
+	setLed(output.Blue, l.pinBlue, l.pwmFrequency, &l.muPinBlue)
 }
 func (l *PiColorLed) off() {
 	l.muColorValue.RLock()
 	defer l.muColorValue.RUnlock()
 
-	setLed(0, l.pinRed, &l.muPinRed)
-	setLed(0, l.pinGreen, &l.muPinGreen)
-	setLed(0, l.pinBlue, &l.muPinBlue)
+	setLed(0, l.pinRed, l.pwmFrequency, &l.muPinRed)
+	setLed(0, l.pinGreen, l.pwmFrequency, &l.muPinGreen)
+	setLed(0, l.pinBlue, l.pwmFrequency, &l.muPinBlue)
 }
 
-func (l *PiColorLed) SetBlink(freq float64) {
-	l.muBlink.Lock()
-	defer l.muBlink.Unlock()
-	if freq > 0 {
-		if !l.blinkEnabled {
-			l.blinkEnabled = true
-			go l.blink(freq)
-		}
-	} else {
-		if l.blinkEnabled {
-			l.blinkEnabled = false
-			l.cancelBlinkChan <- struct{}{}
-		}
-	}
-}
-
-func (l *PiColorLed) blink(freq float64) {
-	log := zap.S().With("func", "blink")
-	ticker := time.NewTicker(time.Duration(float64(time.Second) / freq))
-
-	// Restore values
-	defer l.on()
-
-	for {
-		select {
-		case <-ticker.C:
-		case <-l.cancelBlinkChan:
-			return
-		}
-		log.Debugf("off with color %v", ColorBlack)
-		l.off()
-
-		select {
-		case <-ticker.C:
-		case <-l.cancelBlinkChan:
-			return
-		}
-		log.Debugf("on with color %v", l.Color())
-		l.on()
-	}
-
-}
-
-var setLed = func(v int, led gpio.PinIO, mutex *sync.Mutex) {
+var setLed = func(v int, led gpio.PinIO, freq physic.Frequency, mutex *sync.Mutex) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	lvl := gpio.High
-	if v == 0 {
-		lvl = gpio.Low
-	}
-	err := led.Out(lvl)
+	duty := dutyFromValue(v)
+	err := led.PWM(duty, freq)
 	if err != nil {
-		zap.S().Errorf("unable to sed pin to %v: %v", lvl, err)
+		zap.S().Errorf("unable to set pin duty to %v: %v", duty, err)
 	}
 }
 
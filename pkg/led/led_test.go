@@ -1,12 +1,99 @@
 package led
 
 import (
+	"errors"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"periph.io/x/conn/v3/driver/driverreg"
 	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/conn/v3/physic"
 	"sync"
 	"testing"
 	"time"
 )
 
+func TestOpen(t *testing.T) {
+	hostInitBackup := hostInit
+	defer func() {
+		hostInit = hostInitBackup
+		onceOpen = sync.Once{}
+		errOpen = nil
+	}()
+
+	calls := 0
+	hostInit = func() (*driverreg.State, error) {
+		calls += 1
+		return nil, errors.New("no driver")
+	}
+	onceOpen = sync.Once{}
+	errOpen = nil
+
+	for i := 0; i < 2; i++ {
+		if err := Open(); err == nil {
+			t.Errorf("Open must fail when host init fails")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("host init called %v times, wants %v", calls, 1)
+	}
+	if _, err := NewWithPinNames("GPIO23", "GPIO24", "GPIO25"); err == nil {
+		t.Errorf("NewWithPinNames must fail when host init fails")
+	}
+}
+
+func TestNewWithPinNames(t *testing.T) {
+	pins := []*fakePin{{name: "TEST_RED"}, {name: "TEST_GREEN"}, {name: "TEST_BLUE"}}
+	for _, p := range pins {
+		if err := gpioreg.Register(p); err != nil {
+			t.Fatalf("unable to register fake pin: %v", err)
+		}
+	}
+	defer func() {
+		for _, p := range pins {
+			_ = gpioreg.Unregister(p.name)
+		}
+	}()
+
+	l, err := NewWithPinNames("TEST_RED", "TEST_GREEN", "TEST_BLUE", WithPWMMode(PWMModeOnOff))
+	if err != nil {
+		t.Fatalf("unable to build led: %v", err)
+	}
+	l.SetColor(ColorRed)
+	for i, expected := range []gpio.Level{gpio.High, gpio.Low, gpio.Low} {
+		if lvl, _, _ := pins[i].state(); lvl != expected {
+			t.Errorf("pin %v: %v, wants %v", pins[i].name, lvl, expected)
+		}
+	}
+
+	l.SetColor(ColorAqua)
+	for i, expected := range []gpio.Level{gpio.Low, gpio.High, gpio.High} {
+		if lvl, _, _ := pins[i].state(); lvl != expected {
+			t.Errorf("pin %v: %v, wants %v", pins[i].name, lvl, expected)
+		}
+	}
+
+	l, err = NewWithPinNames("TEST_RED", "TEST_GREEN", "TEST_BLUE", WithPWMMode(PWMModeOnOff), WithPolarity(ActiveLow))
+	if err != nil {
+		t.Fatalf("unable to build active low led: %v", err)
+	}
+	for _, p := range pins {
+		if lvl, _, _ := p.state(); lvl != gpio.High {
+			t.Errorf("pin %v: %v, wants %v after init of active low led", p.name, lvl, gpio.High)
+		}
+	}
+	l.SetColor(ColorRed)
+	for i, expected := range []gpio.Level{gpio.Low, gpio.High, gpio.High} {
+		if lvl, _, _ := pins[i].state(); lvl != expected {
+			t.Errorf("pin %v: %v, wants %v", pins[i].name, lvl, expected)
+		}
+	}
+
+	_, err = NewWithPinNames("TEST_RED", "UNKNOWN_PIN", "TEST_BLUE")
+	if err == nil {
+		t.Errorf("NewWithPinNames must fail with unknown pin name")
+	}
+}
+
 func TestColorLed_Red(t *testing.T) {
 	setLedBackup := setLed
 	defer func() { setLed = setLedBackup }()
@@ -17,7 +104,7 @@ func TestColorLed_Red(t *testing.T) {
 		greenValue int
 		blueValue  int
 	}{}
-	setLed = func(v int, led gpio.PinIO, mutex *sync.Mutex) {
+	setLed = func(v int, led gpio.PinIO, _ physic.Frequency, mutex *sync.Mutex) {
 		mutex.Lock()
 		defer mutex.Unlock()
 		switch led {
@@ -56,7 +143,7 @@ func TestColorLed_Green(t *testing.T) {
 		greenValue int
 		blueValue  int
 	}{}
-	setLed = func(v int, led gpio.PinIO, mutex *sync.Mutex) {
+	setLed = func(v int, led gpio.PinIO, _ physic.Frequency, mutex *sync.Mutex) {
 		mutex.Lock()
 		defer mutex.Unlock()
 		switch led {
@@ -95,7 +182,7 @@ func TestColorLed_Blue(t *testing.T) {
 		greenValue int
 		blueValue  int
 	}{}
-	setLed = func(v int, led gpio.PinIO, mutex *sync.Mutex) {
+	setLed = func(v int, led gpio.PinIO, _ physic.Frequency, mutex *sync.Mutex) {
 		mutex.Lock()
 		defer mutex.Unlock()
 		switch led {
@@ -130,7 +217,7 @@ func TestColorLed_SetBlink(t *testing.T) {
 
 	var muFakeValue sync.Mutex
 	ledColors := make(map[gpio.PinIO]int)
-	setLed = func(v int, led gpio.PinIO, mutex *sync.Mutex) {
+	setLed = func(v int, led gpio.PinIO, _ physic.Frequency, mutex *sync.Mutex) {
 		mutex.Lock()
 		defer mutex.Unlock()
 		muFakeValue.Lock()
@@ -143,28 +230,36 @@ func TestColorLed_SetBlink(t *testing.T) {
 		return ledColors[p]
 	}
 
-	l := New()
+	clk := clock.NewFake(time.Now())
+	advance := func(d time.Duration) {
+		clk.Advance(d)
+		// wait for blink goroutine to handle new time
+		clk.BlockUntil(1)
+	}
+
+	l := New(WithClock(clk))
 	l.SetColor(ColorBlue)
 	v := ledColors[l.pinBlue]
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 255)
 	}
 	l.SetBlink(100)
+	clk.BlockUntil(1)
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 255)
 	}
-	time.Sleep(12 * time.Millisecond)
+	advance(12 * time.Millisecond)
 	v = readValue(l.pinBlue)
 	if v != 0 {
 		t.Errorf("colorValue: %v, wants %v", v, 0)
 	}
-	time.Sleep(12 * time.Millisecond)
+	advance(12 * time.Millisecond)
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 255)
 	}
-	time.Sleep(12 * time.Millisecond)
+	advance(12 * time.Millisecond)
 	v = readValue(l.pinBlue)
 	if v != 0 {
 		t.Errorf("colorValue: %v, wants %v", v, 0)
@@ -172,12 +267,12 @@ func TestColorLed_SetBlink(t *testing.T) {
 
 	// Stop blink
 	l.SetBlink(0)
-	time.Sleep(5 * time.Millisecond)
+	l.wait()
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 255)
 	}
-	time.Sleep(12 * time.Millisecond)
+	clk.Advance(12 * time.Millisecond)
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 255)
@@ -190,7 +285,7 @@ func TestColorLed_SetBlinkAndUpdadeColor(t *testing.T) {
 
 	var muFakeValue sync.Mutex
 	ledColors := make(map[gpio.PinIO]int)
-	setLed = func(v int, led gpio.PinIO, mutex *sync.Mutex) {
+	setLed = func(v int, led gpio.PinIO, _ physic.Frequency, mutex *sync.Mutex) {
 		mutex.Lock()
 		defer mutex.Unlock()
 		muFakeValue.Lock()
@@ -203,29 +298,37 @@ func TestColorLed_SetBlinkAndUpdadeColor(t *testing.T) {
 		return ledColors[p]
 	}
 
-	l := New()
+	clk := clock.NewFake(time.Now())
+	advance := func(d time.Duration) {
+		clk.Advance(d)
+		// wait for blink goroutine to handle new time
+		clk.BlockUntil(1)
+	}
+
+	l := New(WithClock(clk))
 	l.SetColor(ColorBlue)
 	l.SetBlink(100)
+	clk.BlockUntil(1)
 	v := readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 255)
 	}
-	time.Sleep(6 * time.Millisecond)
+	advance(6 * time.Millisecond)
 	l.SetColor(ColorBlue)
 
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 128)
 	}
-	time.Sleep(6 * time.Millisecond)
+	advance(6 * time.Millisecond)
 
-	time.Sleep(12 * time.Millisecond)
+	advance(12 * time.Millisecond)
 
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 128)
 	}
-	time.Sleep(12 * time.Millisecond)
+	advance(12 * time.Millisecond)
 
 	v = readValue(l.pinBlue)
 	if v != 0 {
@@ -234,12 +337,12 @@ func TestColorLed_SetBlinkAndUpdadeColor(t *testing.T) {
 
 	// Stop blink
 	l.SetBlink(0)
-	time.Sleep(5 * time.Millisecond)
+	l.wait()
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 128)
 	}
-	time.Sleep(12 * time.Millisecond)
+	clk.Advance(12 * time.Millisecond)
 	v = readValue(l.pinBlue)
 	if v != 255 {
 		t.Errorf("colorValue: %v, wants %v", v, 128)
@@ -0,0 +1,222 @@
+package led
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"go.uber.org/zap"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/pin"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultPWMFrequency = 100 * physic.Hertz
+
+const (
+	// PWMModeAuto uses hardware pwm when pin supports it, software pwm otherwise
+	PWMModeAuto PWMMode = iota
+	PWMModeSoftware
+	// PWMModeOnOff switches pin on as soon as channel value is not 0
+	PWMModeOnOff
+)
+
+type PWMMode int
+
+func (m PWMMode) String() string {
+	switch m {
+	case PWMModeAuto:
+		return "auto"
+	case PWMModeSoftware:
+		return "software"
+	case PWMModeOnOff:
+		return "onoff"
+	}
+	return fmt.Sprintf("PWMMode(%d)", int(m))
+}
+
+func ParsePWMMode(s string) (PWMMode, error) {
+	for _, m := range []PWMMode{PWMModeAuto, PWMModeSoftware, PWMModeOnOff} {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return PWMModeAuto, fmt.Errorf("invalid pwm mode '%v'", s)
+}
+
+// newPWMPin wraps p with pwm implementation to use according mode and polarity
+func newPWMPin(p gpio.PinIO, mode PWMMode, polarity Polarity, clk clock.Clock) gpio.PinIO {
+	if polarity == ActiveLow {
+		zap.S().Infof("invert output levels on pin %v", p)
+		p = &activeLowPin{PinIO: p}
+	}
+
+	switch mode {
+	case PWMModeAuto:
+		if supportsHardwarePWM(p) {
+			zap.S().Infof("use hardware pwm on pin %v", p)
+			return p
+		}
+		zap.S().Infof("hardware pwm not supported on pin %v, fallback to software pwm", p)
+		return newSoftPWM(p, clk)
+	case PWMModeOnOff:
+		zap.S().Infof("use on/off output on pin %v", p)
+		return &onOffPin{PinIO: p}
+	default:
+		zap.S().Infof("use software pwm on pin %v", p)
+		return newSoftPWM(p, clk)
+	}
+}
+
+func supportsHardwarePWM(p gpio.PinIO) bool {
+	if r, ok := p.(gpio.RealPin); ok {
+		p = r.Real()
+	}
+	pf, ok := p.(pin.PinFunc)
+	if !ok {
+		return false
+	}
+	for _, f := range pf.SupportedFuncs() {
+		if strings.HasPrefix(string(f.Generalize()), string(gpio.PWM)) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// ActiveHigh is used when led is lit with high level (common cathode)
+	ActiveHigh Polarity = iota
+	// ActiveLow is used when led is lit with low level (common anode)
+	ActiveLow
+)
+
+type Polarity int
+
+// activeLowPin inverts levels and duty cycles applied to wrapped pin
+type activeLowPin struct {
+	gpio.PinIO
+}
+
+func (a *activeLowPin) Real() gpio.PinIO {
+	return a.PinIO
+}
+
+func (a *activeLowPin) Out(l gpio.Level) error {
+	return a.PinIO.Out(!l)
+}
+
+func (a *activeLowPin) PWM(duty gpio.Duty, f physic.Frequency) error {
+	return a.PinIO.PWM(gpio.DutyMax-duty, f)
+}
+
+// onOffPin renders any duty greater than 0 as high level
+type onOffPin struct {
+	gpio.PinIO
+}
+
+func (o *onOffPin) PWM(duty gpio.Duty, _ physic.Frequency) error {
+	return o.PinIO.Out(duty > 0)
+}
+
+// softPWM wraps a gpio pin and emulates PWM output by toggling the pin from a goroutine
+type softPWM struct {
+	gpio.PinIO
+	clock clock.Clock
+
+	mu     sync.Mutex
+	cancel chan interface{}
+	done   chan interface{}
+}
+
+func newSoftPWM(p gpio.PinIO, clk clock.Clock) *softPWM {
+	return &softPWM{PinIO: p, clock: clk}
+}
+
+func (s *softPWM) Out(l gpio.Level) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.PinIO.Out(l)
+}
+
+func (s *softPWM) PWM(duty gpio.Duty, f physic.Frequency) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+
+	if duty <= 0 {
+		return s.PinIO.Out(gpio.Low)
+	}
+	if duty >= gpio.DutyMax {
+		return s.PinIO.Out(gpio.High)
+	}
+	if f <= 0 {
+		return fmt.Errorf("invalid pwm frequency %v", f)
+	}
+
+	period := f.Period()
+	onDuration := time.Duration(int64(period) * int64(duty) / int64(gpio.DutyMax))
+
+	s.cancel = make(chan interface{})
+	s.done = make(chan interface{})
+	go s.run(onDuration, period-onDuration, s.cancel, s.done)
+	return nil
+}
+
+func (s *softPWM) Halt() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.PinIO.Halt()
+}
+
+// stop cancels running pwm goroutine, must be called with mu locked
+func (s *softPWM) stop() {
+	if s.cancel == nil {
+		return
+	}
+	close(s.cancel)
+	<-s.done
+	s.cancel = nil
+	s.done = nil
+}
+
+func (s *softPWM) run(onDuration, offDuration time.Duration, cancel <-chan interface{}, done chan<- interface{}) {
+	defer close(done)
+
+	timer := s.clock.NewTimer(onDuration)
+	timer.Stop()
+	defer timer.Stop()
+
+	lvl := gpio.High
+	for {
+		if err := s.PinIO.Out(lvl); err != nil {
+			zap.S().Errorf("unable to set pin %v to %v: %v", s.PinIO, lvl, err)
+		}
+		if lvl == gpio.High {
+			timer.Reset(onDuration)
+		} else {
+			timer.Reset(offDuration)
+		}
+
+		select {
+		case <-timer.C():
+		case <-cancel:
+			return
+		}
+		lvl = !lvl
+	}
+}
+
+// dutyFromValue converts a 0-255 channel value to gpio duty cycle
+func dutyFromValue(v int) gpio.Duty {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return gpio.DutyMax
+	}
+	return gpio.Duty(int64(v) * int64(gpio.DutyMax) / 255)
+}
@@ -0,0 +1,292 @@
+package led
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/pin"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakePin struct {
+	gpio.PinIO
+
+	mu     sync.Mutex
+	level  gpio.Level
+	highs  int
+	lows   int
+	halted bool
+	funcs  []pin.Func
+	name   string
+}
+
+func (f *fakePin) String() string {
+	return "fakePin(" + f.name + ")"
+}
+
+func (f *fakePin) Name() string {
+	return f.name
+}
+
+func (f *fakePin) Func() pin.Func {
+	return gpio.OUT
+}
+
+func (f *fakePin) SupportedFuncs() []pin.Func {
+	return f.funcs
+}
+
+func (f *fakePin) SetFunc(_ pin.Func) error {
+	return nil
+}
+
+func (f *fakePin) Out(l gpio.Level) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.level = l
+	if l == gpio.High {
+		f.highs += 1
+	} else {
+		f.lows += 1
+	}
+	return nil
+}
+
+func (f *fakePin) Halt() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.halted = true
+	return nil
+}
+
+func (f *fakePin) state() (gpio.Level, int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.level, f.highs, f.lows
+}
+
+func TestSoftPWM_PWM(t *testing.T) {
+	cases := []struct {
+		name          string
+		duty          gpio.Duty
+		expectedLevel gpio.Level
+	}{
+		{"off", 0, gpio.Low},
+		{"on", gpio.DutyMax, gpio.High},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pin := fakePin{}
+			clk := clock.NewFake(time.Now())
+			p := newSoftPWM(&pin, clk)
+			if err := p.PWM(c.duty, DefaultPWMFrequency); err != nil {
+				t.Errorf("unable to set duty: %v", err)
+			}
+			// Steady level doesn't need pwm goroutine
+			clk.Advance(30 * time.Millisecond)
+			lvl, highs, lows := pin.state()
+			if lvl != c.expectedLevel {
+				t.Errorf("level: %v, wants %v", lvl, c.expectedLevel)
+			}
+			if highs+lows != 1 {
+				t.Errorf("pin toggled %v times, wants 1", highs+lows)
+			}
+		})
+	}
+}
+
+func TestSoftPWM_PWMHalfDuty(t *testing.T) {
+	pin := fakePin{}
+	clk := clock.NewFake(time.Now())
+	p := newSoftPWM(&pin, clk)
+
+	if err := p.PWM(gpio.DutyHalf, 1*physic.KiloHertz); err != nil {
+		t.Errorf("unable to set duty: %v", err)
+	}
+	clk.BlockUntil(1)
+	for i := 0; i < 3; i++ {
+		clk.Advance(500 * time.Microsecond)
+		clk.BlockUntil(1)
+	}
+	_, highs, lows := pin.state()
+	if highs != 2 || lows != 2 {
+		t.Errorf("pin toggled %v highs, %v lows, wants 2 of each", highs, lows)
+	}
+
+	// Out must stop pwm goroutine
+	if err := p.Out(gpio.Low); err != nil {
+		t.Errorf("unable to set level: %v", err)
+	}
+	_, highs, lows = pin.state()
+	clk.Advance(5 * time.Millisecond)
+	lvl, h, l := pin.state()
+	if lvl != gpio.Low {
+		t.Errorf("level: %v, wants %v", lvl, gpio.Low)
+	}
+	if h != highs || l != lows {
+		t.Errorf("pin toggled after Out call")
+	}
+}
+
+func TestSoftPWM_PWMInvalidFrequency(t *testing.T) {
+	p := newSoftPWM(&fakePin{}, clock.New())
+	if err := p.PWM(gpio.DutyHalf, 0); err == nil {
+		t.Errorf("PWM with invalid frequency must fail")
+	}
+}
+
+func TestSoftPWM_Halt(t *testing.T) {
+	pin := fakePin{}
+	clk := clock.NewFake(time.Now())
+	p := newSoftPWM(&pin, clk)
+	if err := p.PWM(gpio.DutyHalf, 1*physic.KiloHertz); err != nil {
+		t.Errorf("unable to set duty: %v", err)
+	}
+	clk.BlockUntil(1)
+	if err := p.Halt(); err != nil {
+		t.Errorf("unable to halt pin: %v", err)
+	}
+	_, highs, lows := pin.state()
+	clk.Advance(5 * time.Millisecond)
+	_, h, l := pin.state()
+	if h != highs || l != lows {
+		t.Errorf("pin toggled after Halt call")
+	}
+	if !pin.halted {
+		t.Errorf("underlying pin not halted")
+	}
+}
+
+func TestDutyFromValue(t *testing.T) {
+	cases := []struct {
+		value        int
+		expectedDuty gpio.Duty
+	}{
+		{-1, 0},
+		{0, 0},
+		{255, gpio.DutyMax},
+		{300, gpio.DutyMax},
+		{128, gpio.Duty(128 * int64(gpio.DutyMax) / 255)},
+	}
+	for _, c := range cases {
+		if d := dutyFromValue(c.value); d != c.expectedDuty {
+			t.Errorf("dutyFromValue(%v): %v, wants %v", c.value, d, c.expectedDuty)
+		}
+	}
+}
+
+func TestNewPWMPin(t *testing.T) {
+	hwPin := fakePin{funcs: []pin.Func{gpio.IN, gpio.OUT, "PWM0"}}
+	swPin := fakePin{funcs: []pin.Func{gpio.IN, gpio.OUT}}
+
+	cases := []struct {
+		name     string
+		pin      *fakePin
+		mode     PWMMode
+		expected string
+	}{
+		{"auto with hardware pwm", &hwPin, PWMModeAuto, "hardware"},
+		{"auto without hardware pwm", &swPin, PWMModeAuto, "software"},
+		{"software", &hwPin, PWMModeSoftware, "software"},
+		{"on/off", &hwPin, PWMModeOnOff, "onoff"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var kind string
+			switch p := newPWMPin(c.pin, c.mode, ActiveHigh, clock.New()).(type) {
+			case *softPWM:
+				kind = "software"
+			case *onOffPin:
+				kind = "onoff"
+			case *fakePin:
+				if p == c.pin {
+					kind = "hardware"
+				}
+			}
+			if kind != c.expected {
+				t.Errorf("newPWMPin(%v): %v pin, wants %v", c.mode, kind, c.expected)
+			}
+		})
+	}
+}
+
+func TestOnOffPin_PWM(t *testing.T) {
+	pin := fakePin{}
+	p := onOffPin{PinIO: &pin}
+
+	cases := []struct {
+		duty          gpio.Duty
+		expectedLevel gpio.Level
+	}{
+		{gpio.DutyHalf, gpio.High},
+		{0, gpio.Low},
+		{1, gpio.High},
+	}
+	for _, c := range cases {
+		if err := p.PWM(c.duty, DefaultPWMFrequency); err != nil {
+			t.Errorf("unable to set duty: %v", err)
+		}
+		if lvl, _, _ := pin.state(); lvl != c.expectedLevel {
+			t.Errorf("PWM(%v): %v, wants %v", c.duty, lvl, c.expectedLevel)
+		}
+	}
+}
+
+func TestParsePWMMode(t *testing.T) {
+	for _, m := range []PWMMode{PWMModeAuto, PWMModeSoftware, PWMModeOnOff} {
+		parsed, err := ParsePWMMode(m.String())
+		if err != nil {
+			t.Errorf("unable to parse %v: %v", m, err)
+		}
+		if parsed != m {
+			t.Errorf("ParsePWMMode(%v): %v, wants %v", m.String(), parsed, m)
+		}
+	}
+	if _, err := ParsePWMMode("invalid"); err == nil {
+		t.Errorf("ParsePWMMode must fail on invalid value")
+	}
+}
+
+func TestNewPWMPin_ActiveLow(t *testing.T) {
+	hwPin := fakePin{funcs: []pin.Func{gpio.IN, gpio.OUT, "PWM0"}}
+
+	p := newPWMPin(&hwPin, PWMModeAuto, ActiveLow, clock.New())
+	a, ok := p.(*activeLowPin)
+	if !ok {
+		t.Fatalf("newPWMPin(%v): %T, wants %T", ActiveLow, p, a)
+	}
+	if a.PinIO != &hwPin {
+		t.Errorf("hardware pwm not detected behind active low pin")
+	}
+}
+
+func TestActiveLowPin(t *testing.T) {
+	pin := fakePin{}
+	p := newPWMPin(&pin, PWMModeOnOff, ActiveLow, clock.New())
+
+	cases := []struct {
+		duty          gpio.Duty
+		expectedLevel gpio.Level
+	}{
+		{0, gpio.High},
+		{gpio.DutyMax, gpio.Low},
+		{gpio.DutyHalf, gpio.Low},
+	}
+	for _, c := range cases {
+		if err := p.PWM(c.duty, DefaultPWMFrequency); err != nil {
+			t.Errorf("unable to set duty: %v", err)
+		}
+		if lvl, _, _ := pin.state(); lvl != c.expectedLevel {
+			t.Errorf("PWM(%v): %v, wants %v", c.duty, lvl, c.expectedLevel)
+		}
+	}
+
+	if err := p.Out(gpio.High); err != nil {
+		t.Errorf("unable to set level: %v", err)
+	}
+	if lvl, _, _ := pin.state(); lvl != gpio.Low {
+		t.Errorf("Out(%v): %v, wants %v", gpio.High, lvl, gpio.Low)
+	}
+}
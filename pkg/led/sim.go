@@ -0,0 +1,104 @@
+package led
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// maxSimEvents limits history kept by SimLed
+const maxSimEvents = 1024
+
+// SimEvent is a state change recorded by SimLed
+type SimEvent struct {
+	Time  time.Time
+	Color Color
+	Blink BlinkPattern
+}
+
+// SimLed is a virtual led that logs and records every color/blink change, it doesn't need any hardware
+type SimLed struct {
+	clock clock.Clock
+
+	mu     sync.RWMutex
+	color  Color
+	blink  BlinkPattern
+	events []SimEvent
+}
+
+// SimOption configures a SimLed
+type SimOption func(s *SimLed)
+
+// WithSimClock configures clock used to timestamp events, default is system clock
+func WithSimClock(c clock.Clock) SimOption {
+	return func(s *SimLed) {
+		s.clock = c
+	}
+}
+
+func NewSimLed(opts ...SimOption) *SimLed {
+	s := SimLed{color: ColorBlack, clock: clock.New()}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return &s
+}
+
+func (s *SimLed) SetColor(color Color) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if color == s.color {
+		return
+	}
+	s.color = color
+	s.record()
+}
+
+func (s *SimLed) SetBlink(freq float64) {
+	s.SetBlinkPattern(BlinkFrequency(freq))
+}
+
+func (s *SimLed) SetBlinkPattern(pattern BlinkPattern) {
+	if !pattern.Enabled() {
+		pattern = BlinkPattern{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pattern == s.blink {
+		return
+	}
+	s.blink = pattern
+	s.record()
+}
+
+func (s *SimLed) Color() Color {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.color
+}
+
+func (s *SimLed) Blink() BlinkPattern {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.blink
+}
+
+// Events returns recorded changes, oldest first
+func (s *SimLed) Events() []SimEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := make([]SimEvent, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// record appends current state to history, must be called with mu locked
+func (s *SimLed) record() {
+	evt := SimEvent{Time: s.clock.Now(), Color: s.color, Blink: s.blink}
+	zap.S().Infof("sim led: color=%v, blink=%v", evt.Color, evt.Blink)
+	if len(s.events) >= maxSimEvents {
+		s.events = append(s.events[:0], s.events[1:]...)
+	}
+	s.events = append(s.events, evt)
+}
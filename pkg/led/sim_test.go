@@ -0,0 +1,60 @@
+package led
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"testing"
+	"time"
+)
+
+func TestSimLed(t *testing.T) {
+	start := time.Now()
+	clk := clock.NewFake(start)
+	l := NewSimLed(WithSimClock(clk))
+
+	l.SetColor(ColorRed)
+	l.SetColor(ColorRed)
+	clk.Advance(time.Second)
+	l.SetBlink(2)
+	clk.Advance(time.Second)
+	l.SetColor(ColorBlue)
+	clk.Advance(time.Second)
+	l.SetBlink(0)
+
+	if l.Color() != ColorBlue {
+		t.Errorf("%T.Color(): %v, wants %v", l, l.Color(), ColorBlue)
+	}
+	if l.Blink().Enabled() {
+		t.Errorf("%T.Blink(): %v, wants disabled blink", l, l.Blink())
+	}
+
+	expected := []SimEvent{
+		{Time: start, Color: ColorRed},
+		{Time: start.Add(time.Second), Color: ColorRed, Blink: BlinkFrequency(2)},
+		{Time: start.Add(2 * time.Second), Color: ColorBlue, Blink: BlinkFrequency(2)},
+		{Time: start.Add(3 * time.Second), Color: ColorBlue},
+	}
+	events := l.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("%v events recorded, wants %v: %v", len(events), len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].Color != e.Color || events[i].Blink != e.Blink || !events[i].Time.Equal(e.Time) {
+			t.Errorf("event %v: %v, wants %v", i, events[i], e)
+		}
+	}
+}
+
+func TestSimLed_MaxEvents(t *testing.T) {
+	l := NewSimLed()
+	for i := 0; i < maxSimEvents+10; i++ {
+		l.SetColor(Color{Red: i % 256, Green: i / 256})
+	}
+	events := l.Events()
+	if len(events) != maxSimEvents {
+		t.Errorf("%v events recorded, wants %v", len(events), maxSimEvents)
+	}
+	last := maxSimEvents + 9
+	if events[len(events)-1].Color != (Color{Red: last % 256, Green: last / 256}) {
+		t.Errorf("last event: %v, wants most recent color", events[len(events)-1])
+	}
+}
@@ -0,0 +1,110 @@
+package led
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"sync"
+)
+
+// strip holds pixels state shared by addressable led strips, render writes pixels to hardware
+type strip struct {
+	muPixels     sync.RWMutex
+	pixels       []Color
+	currentColor Color
+
+	render func(pixels []Color)
+	clock  clock.Clock
+
+	*blinker
+	*calibrator
+}
+
+// StripOption configures WS2812 and APA102 strips
+type StripOption func(s *strip)
+
+// WithStripClock configures clock used by blink, default is system clock
+func WithStripClock(c clock.Clock) StripOption {
+	return func(s *strip) {
+		s.clock = c
+	}
+}
+
+func newStrip(pixelCount int, render func(pixels []Color), opts ...StripOption) *strip {
+	s := strip{
+		pixels:       make([]Color, pixelCount),
+		currentColor: ColorBlack,
+		render:       render,
+		clock:        clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	s.blinker = newBlinker(s.on, s.off, s.clock)
+	s.calibrator = newCalibrator(s.show)
+	return &s
+}
+
+func (s *strip) Len() int {
+	return len(s.pixels)
+}
+
+// SetColor applies color to all pixels
+func (s *strip) SetColor(color Color) {
+	s.muPixels.Lock()
+	s.currentColor = color
+	for i := range s.pixels {
+		s.pixels[i] = color
+	}
+	s.muPixels.Unlock()
+
+	s.show()
+}
+
+func (s *strip) Color() Color {
+	s.muPixels.RLock()
+	defer s.muPixels.RUnlock()
+	return s.currentColor
+}
+
+func (s *strip) SetPixel(index int, color Color) error {
+	s.muPixels.Lock()
+	if index < 0 || index >= len(s.pixels) {
+		s.muPixels.Unlock()
+		return fmt.Errorf("pixel index %v out of range [0, %v)", index, len(s.pixels))
+	}
+	s.pixels[index] = color
+	s.muPixels.Unlock()
+
+	s.show()
+	return nil
+}
+
+func (s *strip) Pixels() []Color {
+	s.muPixels.RLock()
+	defer s.muPixels.RUnlock()
+	pixels := make([]Color, len(s.pixels))
+	copy(pixels, s.pixels)
+	return pixels
+}
+
+func (s *strip) on() {
+	pixels := s.Pixels()
+	for i, p := range pixels {
+		pixels[i] = s.correct(p)
+	}
+	s.render(pixels)
+}
+
+func (s *strip) off() {
+	s.render(make([]Color, len(s.pixels)))
+}
+
+func clampChannel(v int) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
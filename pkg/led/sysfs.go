@@ -0,0 +1,226 @@
+package led
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultSysfsRoot = "/sys/class/leds"
+
+// SysfsLed drives a led exposed by linux kernel led class (<root>/<name>/brightness, ...).
+//
+// Multicolor leds are driven with multi_intensity file, mono leds are lit with brightness of
+// highest color channel. Blink uses kernel timer trigger when available.
+type SysfsLed struct {
+	dir           string
+	maxBrightness int
+	// channels contains multi_index content, nil for mono led
+	channels     []string
+	timerTrigger bool
+	clock        clock.Clock
+
+	muColorValue sync.RWMutex
+	currentColor Color
+
+	muBlink     sync.Mutex
+	pattern     BlinkPattern
+	timerActive bool
+	fallback    *blinker
+
+	*calibrator
+}
+
+// SysfsOption configures a SysfsLed
+type SysfsOption func(l *SysfsLed)
+
+// WithSysfsClock configures clock used by blink when kernel timer trigger can't render pattern,
+// default is system clock
+func WithSysfsClock(c clock.Clock) SysfsOption {
+	return func(l *SysfsLed) {
+		l.clock = c
+	}
+}
+
+func NewSysfsLed(root, name string, opts ...SysfsOption) (*SysfsLed, error) {
+	dir := filepath.Join(root, name)
+
+	maxBrightness, err := readSysfsInt(filepath.Join(dir, "max_brightness"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read max brightness of led '%v': %v", name, err)
+	}
+
+	var channels []string
+	index, err := os.ReadFile(filepath.Join(dir, "multi_index"))
+	if err == nil {
+		channels = strings.Fields(string(index))
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to read multi_index of led '%v': %v", name, err)
+	}
+
+	timerTrigger := false
+	if triggers, err := os.ReadFile(filepath.Join(dir, "trigger")); err == nil {
+		for _, t := range strings.Fields(string(triggers)) {
+			if strings.Trim(t, "[]") == "timer" {
+				timerTrigger = true
+				break
+			}
+		}
+	}
+
+	l := SysfsLed{
+		dir:           dir,
+		maxBrightness: maxBrightness,
+		channels:      channels,
+		timerTrigger:  timerTrigger,
+		currentColor:  ColorBlack,
+		clock:         clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	l.fallback = newBlinker(l.on, l.off, l.clock)
+	l.calibrator = newCalibrator(func() { l.SetColor(l.Color()) })
+	zap.S().Infof("use sysfs led %v, multicolor: %v, timer trigger: %v", dir, channels != nil, timerTrigger)
+
+	l.on()
+	return &l, nil
+}
+
+func (l *SysfsLed) SetColor(color Color) {
+	l.muColorValue.Lock()
+	l.currentColor = color
+	l.muColorValue.Unlock()
+
+	l.muBlink.Lock()
+	defer l.muBlink.Unlock()
+	l.apply(false)
+}
+
+func (l *SysfsLed) Color() Color {
+	l.muColorValue.RLock()
+	defer l.muColorValue.RUnlock()
+	return l.currentColor
+}
+
+func (l *SysfsLed) SetBlink(freq float64) {
+	l.SetBlinkPattern(BlinkFrequency(freq))
+}
+
+// SetBlinkPattern uses kernel timer trigger for continuous patterns, bursts are rendered by a goroutine
+func (l *SysfsLed) SetBlinkPattern(pattern BlinkPattern) {
+	l.muBlink.Lock()
+	defer l.muBlink.Unlock()
+	if pattern == l.pattern {
+		return
+	}
+	l.pattern = pattern
+	l.apply(true)
+}
+
+// apply renders color and blink pattern, with kernel timer trigger if possible. A dark color stops
+// timer trigger: brightness can't be written to 0 while it's active. Must be called with muBlink locked
+func (l *SysfsLed) apply(patternChanged bool) {
+	output := l.correct(l.Color())
+	if l.timerTrigger && l.pattern.Enabled() && l.pattern.Count <= 0 && l.brightness(output) > 0 {
+		l.fallback.SetBlinkPattern(BlinkPattern{})
+		if !l.timerActive || patternChanged {
+			l.write("trigger", "timer")
+			l.write("delay_on", strconv.Itoa(delayMillis(l.pattern.On)))
+			l.write("delay_off", strconv.Itoa(delayMillis(l.pattern.Off)))
+			l.timerActive = true
+		}
+		l.writeIntensities(output)
+		l.write("brightness", strconv.Itoa(l.brightness(output)))
+		return
+	}
+
+	if l.timerActive {
+		l.write("trigger", "none")
+		l.timerActive = false
+	}
+	l.fallback.SetBlinkPattern(l.pattern)
+	l.fallback.show()
+}
+
+func delayMillis(d time.Duration) int {
+	ms := int(d / time.Millisecond)
+	if ms < 1 {
+		return 1
+	}
+	return ms
+}
+
+func (l *SysfsLed) on() {
+	color := l.correct(l.Color())
+	l.writeIntensities(color)
+	l.write("brightness", strconv.Itoa(l.brightness(color)))
+}
+
+func (l *SysfsLed) off() {
+	l.write("brightness", "0")
+}
+
+func (l *SysfsLed) writeIntensities(color Color) {
+	if l.channels == nil {
+		return
+	}
+	intensities := make([]string, len(l.channels))
+	for i, c := range l.channels {
+		v := 0
+		switch c {
+		case "red":
+			v = color.Red
+		case "green":
+			v = color.Green
+		case "blue":
+			v = color.Blue
+		}
+		intensities[i] = strconv.Itoa(l.scale(v))
+	}
+	l.write("multi_intensity", strings.Join(intensities, " "))
+}
+
+// brightness computes value of brightness file to render color
+func (l *SysfsLed) brightness(color Color) int {
+	if l.channels != nil {
+		if color == ColorBlack {
+			return 0
+		}
+		return l.maxBrightness
+	}
+	v := color.Red
+	if color.Green > v {
+		v = color.Green
+	}
+	if color.Blue > v {
+		v = color.Blue
+	}
+	return l.scale(v)
+}
+
+// scale converts a 0-255 channel value to 0-max_brightness range
+func (l *SysfsLed) scale(v int) int {
+	return int(clampChannel(v)) * l.maxBrightness / 255
+}
+
+func (l *SysfsLed) write(file, value string) {
+	err := os.WriteFile(filepath.Join(l.dir, file), []byte(value), 0644)
+	if err != nil {
+		zap.S().Errorf("unable to write '%v' to led %v: %v", value, file, err)
+	}
+}
+
+func readSysfsInt(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
@@ -0,0 +1,239 @@
+package led
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func initSysfsLed(t *testing.T, files map[string]string) (string, string) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "rgb:status")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("unable to create led dir: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("unable to init file %v: %v", name, err)
+		}
+	}
+	return root, dir
+}
+
+func readSysfsFile(t *testing.T, dir, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Errorf("unable to read file %v: %v", name, err)
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func TestSysfsLed_SetColorMulticolor(t *testing.T) {
+	root, dir := initSysfsLed(t, map[string]string{
+		"max_brightness":  "100\n",
+		"brightness":      "0\n",
+		"multi_index":     "green red blue\n",
+		"multi_intensity": "0 0 0\n",
+		"trigger":         "[none] timer heartbeat\n",
+	})
+
+	l, err := NewSysfsLed(root, "rgb:status")
+	if err != nil {
+		t.Fatalf("unable to init sysfs led: %v", err)
+	}
+
+	l.SetColor(ColorPurple)
+	if v := readSysfsFile(t, dir, "multi_intensity"); v != "0 100 100" {
+		t.Errorf("multi_intensity: %v, wants %v", v, "0 100 100")
+	}
+	if v := readSysfsFile(t, dir, "brightness"); v != "100" {
+		t.Errorf("brightness: %v, wants %v", v, "100")
+	}
+	if l.Color() != ColorPurple {
+		t.Errorf("%T.Color(): %v, wants %v", l, l.Color(), ColorPurple)
+	}
+
+	l.SetColor(ColorBlack)
+	if v := readSysfsFile(t, dir, "brightness"); v != "0" {
+		t.Errorf("brightness: %v, wants %v", v, "0")
+	}
+}
+
+func TestSysfsLed_SetColorMono(t *testing.T) {
+	root, dir := initSysfsLed(t, map[string]string{
+		"max_brightness": "255\n",
+		"brightness":     "0\n",
+		"trigger":        "[none] heartbeat\n",
+	})
+
+	l, err := NewSysfsLed(root, "rgb:status")
+	if err != nil {
+		t.Fatalf("unable to init sysfs led: %v", err)
+	}
+
+	l.SetColor(ColorTurquoise)
+	if v := readSysfsFile(t, dir, "brightness"); v != "224" {
+		t.Errorf("brightness: %v, wants %v", v, "224")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "multi_intensity")); !os.IsNotExist(err) {
+		t.Errorf("multi_intensity must not be written on mono led")
+	}
+}
+
+func TestSysfsLed_SetColorMonoDarkWhileBlinking(t *testing.T) {
+	root, dir := initSysfsLed(t, map[string]string{
+		"max_brightness": "255\n",
+		"brightness":     "0\n",
+		"trigger":        "[none] timer heartbeat\n",
+	})
+
+	l, err := NewSysfsLed(root, "rgb:status", WithSysfsClock(clock.NewFake(time.Now())))
+	if err != nil {
+		t.Fatalf("unable to init sysfs led: %v", err)
+	}
+	l.SetColor(ColorWhite)
+	l.SetBlink(2)
+	if v := readSysfsFile(t, dir, "trigger"); v != "timer" {
+		t.Errorf("trigger: %v, wants %v", v, "timer")
+	}
+
+	l.SetColor(ColorBlack)
+	if v := readSysfsFile(t, dir, "trigger"); v != "none" {
+		t.Errorf("trigger with dark color: %v, wants %v", v, "none")
+	}
+	if v := readSysfsFile(t, dir, "brightness"); v != "0" {
+		t.Errorf("brightness with dark color: %v, wants %v", v, "0")
+	}
+	l.SetBlink(0)
+	l.fallback.wait()
+}
+
+func TestSysfsLed_SetBlinkTimerTrigger(t *testing.T) {
+	root, dir := initSysfsLed(t, map[string]string{
+		"max_brightness":  "255\n",
+		"brightness":      "0\n",
+		"multi_index":     "red green blue\n",
+		"multi_intensity": "0 0 0\n",
+		"trigger":         "[none] timer heartbeat\n",
+	})
+
+	l, err := NewSysfsLed(root, "rgb:status")
+	if err != nil {
+		t.Fatalf("unable to init sysfs led: %v", err)
+	}
+	l.SetColor(ColorRed)
+
+	l.SetBlink(2)
+	if v := readSysfsFile(t, dir, "trigger"); v != "timer" {
+		t.Errorf("trigger: %v, wants %v", v, "timer")
+	}
+	for _, f := range []string{"delay_on", "delay_off"} {
+		if v := readSysfsFile(t, dir, f); v != "500" {
+			t.Errorf("%v: %v, wants %v", f, v, "500")
+		}
+	}
+
+	// Unchanged pattern doesn't restart timer
+	if err := os.WriteFile(filepath.Join(dir, "trigger"), []byte("heartbeat"), 0644); err != nil {
+		t.Fatalf("unable to write trigger: %v", err)
+	}
+	l.SetBlink(2)
+	if v := readSysfsFile(t, dir, "trigger"); v != "heartbeat" {
+		t.Errorf("trigger after same pattern: %v, wants %v", v, "heartbeat")
+	}
+	l.SetBlinkPattern(BlinkFrequency(4))
+	if v := readSysfsFile(t, dir, "trigger"); v != "timer" {
+		t.Errorf("trigger after pattern change: %v, wants %v", v, "timer")
+	}
+	if v := readSysfsFile(t, dir, "delay_on"); v != "250" {
+		t.Errorf("delay_on after pattern change: %v, wants %v", v, "250")
+	}
+
+	// Color change must not disable trigger
+	l.SetColor(ColorBlue)
+	if v := readSysfsFile(t, dir, "trigger"); v != "timer" {
+		t.Errorf("trigger after color change: %v, wants %v", v, "timer")
+	}
+	if v := readSysfsFile(t, dir, "multi_intensity"); v != "0 0 255" {
+		t.Errorf("multi_intensity: %v, wants %v", v, "0 0 255")
+	}
+
+	// Dark color stops trigger
+	l.SetColor(ColorBlack)
+	if v := readSysfsFile(t, dir, "trigger"); v != "none" {
+		t.Errorf("trigger with dark color: %v, wants %v", v, "none")
+	}
+	if v := readSysfsFile(t, dir, "brightness"); v != "0" {
+		t.Errorf("brightness with dark color: %v, wants %v", v, "0")
+	}
+
+	l.SetColor(ColorGreen)
+	if v := readSysfsFile(t, dir, "trigger"); v != "timer" {
+		t.Errorf("trigger after dark color: %v, wants %v", v, "timer")
+	}
+	if v := readSysfsFile(t, dir, "delay_on"); v != "250" {
+		t.Errorf("delay_on after dark color: %v, wants %v", v, "250")
+	}
+	l.SetBlink(0)
+	if v := readSysfsFile(t, dir, "trigger"); v != "none" {
+		t.Errorf("trigger: %v, wants %v", v, "none")
+	}
+	if v := readSysfsFile(t, dir, "brightness"); v != "255" {
+		t.Errorf("brightness: %v, wants %v", v, "255")
+	}
+	if v := readSysfsFile(t, dir, "multi_intensity"); v != "0 255 0" {
+		t.Errorf("multi_intensity: %v, wants %v", v, "0 255 0")
+	}
+}
+
+func TestNewSysfsLed_Missing(t *testing.T) {
+	if _, err := NewSysfsLed(t.TempDir(), "unknown"); err == nil {
+		t.Errorf("NewSysfsLed must fail when led doesn't exist")
+	}
+}
+
+func TestSysfsLed_SetBlinkPatternBurst(t *testing.T) {
+	root, dir := initSysfsLed(t, map[string]string{
+		"max_brightness": "255\n",
+		"brightness":     "0\n",
+		"trigger":        "[none] timer heartbeat\n",
+	})
+
+	clk := clock.NewFake(time.Now())
+	l, err := NewSysfsLed(root, "rgb:status", WithSysfsClock(clk))
+	if err != nil {
+		t.Fatalf("unable to init sysfs led: %v", err)
+	}
+	l.SetColor(ColorWhite)
+
+	l.SetBlink(2)
+	if v := readSysfsFile(t, dir, "trigger"); v != "timer" {
+		t.Errorf("trigger: %v, wants %v", v, "timer")
+	}
+
+	// Bursts can't be rendered by timer trigger
+	l.SetBlinkPattern(BlinkPattern{On: time.Hour, Off: time.Hour, Count: 2, Pause: time.Hour})
+	if v := readSysfsFile(t, dir, "trigger"); v != "none" {
+		t.Errorf("trigger: %v, wants %v", v, "none")
+	}
+	if v := readSysfsFile(t, dir, "brightness"); v != "255" {
+		t.Errorf("brightness: %v, wants %v", v, "255")
+	}
+
+	// Burst is rendered by goroutine driven by clock
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	clk.BlockUntil(1)
+	if v := readSysfsFile(t, dir, "brightness"); v != "0" {
+		t.Errorf("brightness after on phase: %v, wants %v", v, "0")
+	}
+
+	l.SetBlinkPattern(BlinkPattern{})
+	l.fallback.wait()
+	if v := readSysfsFile(t, dir, "brightness"); v != "255" {
+		t.Errorf("brightness after blink stop: %v, wants %v", v, "255")
+	}
+}
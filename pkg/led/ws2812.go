@@ -0,0 +1,84 @@
+package led
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi"
+	"sync"
+)
+
+const (
+	// ws2812Frequency makes 3 spi bits last a ws2812 bit period (1.25µs)
+	ws2812Frequency = 2400 * physic.KiloHertz
+	// ws2812ResetBytes holds line low long enough (300µs) to latch pixels
+	ws2812ResetBytes = 90
+)
+
+// WS2812 drives a strip of WS2812/NeoPixel leds connected to spi MOSI pin.
+//
+// Each ws2812 bit is encoded with 3 spi bits: 0b110 for 1 and 0b100 for 0.
+type WS2812 struct {
+	conn spi.Conn
+
+	muTx sync.Mutex
+	buf  []byte
+
+	*strip
+}
+
+func NewWS2812(p spi.Port, pixelCount int, opts ...StripOption) (*WS2812, error) {
+	if pixelCount <= 0 {
+		return nil, fmt.Errorf("invalid pixel count %v", pixelCount)
+	}
+	c, err := p.Connect(ws2812Frequency, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to spi port: %v", err)
+	}
+
+	s := WS2812{
+		conn: c,
+		buf:  make([]byte, pixelCount*3*3+ws2812ResetBytes),
+	}
+	s.strip = newStrip(pixelCount, s.write, opts...)
+	s.off()
+	return &s, nil
+}
+
+func (s *WS2812) write(pixels []Color) {
+	s.muTx.Lock()
+	defer s.muTx.Unlock()
+
+	encodeWS2812(pixels, s.buf)
+	if err := s.conn.Tx(s.buf, nil); err != nil {
+		zap.S().Errorf("unable to write ws2812 pixels: %v", err)
+	}
+}
+
+// encodeWS2812 writes pixels in GRB order to dst followed by reset bytes
+func encodeWS2812(pixels []Color, dst []byte) {
+	for i, p := range pixels {
+		offset := i * 9
+		encodeWS2812Byte(clampChannel(p.Green), dst[offset:offset+3])
+		encodeWS2812Byte(clampChannel(p.Red), dst[offset+3:offset+6])
+		encodeWS2812Byte(clampChannel(p.Blue), dst[offset+6:offset+9])
+	}
+	for i := len(pixels) * 9; i < len(dst); i++ {
+		dst[i] = 0
+	}
+}
+
+func encodeWS2812Byte(b byte, dst []byte) {
+	var v uint32
+	for i := 7; i >= 0; i-- {
+		v <<= 3
+		if b&(1<<uint(i)) != 0 {
+			v |= 0b110
+		} else {
+			v |= 0b100
+		}
+	}
+	dst[0] = byte(v >> 16)
+	dst[1] = byte(v >> 8)
+	dst[2] = byte(v)
+}
@@ -0,0 +1,135 @@
+package led
+
+import (
+	"bytes"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"periph.io/x/conn/v3/spi/spitest"
+	"testing"
+	"time"
+)
+
+var (
+	ws2812Zero = []byte{0x92, 0x49, 0x24}
+	ws2812Full = []byte{0xDB, 0x6D, 0xB6}
+)
+
+func ws2812Frame(channels ...[]byte) []byte {
+	frame := bytes.Join(channels, nil)
+	return append(frame, make([]byte, ws2812ResetBytes)...)
+}
+
+func TestEncodeWS2812Byte(t *testing.T) {
+	cases := []struct {
+		value    byte
+		expected []byte
+	}{
+		{0x00, ws2812Zero},
+		{0xFF, ws2812Full},
+		{0xA5, []byte{0xD3, 0x49, 0xA6}},
+	}
+	for _, c := range cases {
+		dst := make([]byte, 3)
+		encodeWS2812Byte(c.value, dst)
+		if !bytes.Equal(dst, c.expected) {
+			t.Errorf("encodeWS2812Byte(%#x): %#v, wants %#v", c.value, dst, c.expected)
+		}
+	}
+}
+
+func TestWS2812_SetColor(t *testing.T) {
+	port := spitest.Record{}
+	s, err := NewWS2812(&port, 2)
+	if err != nil {
+		t.Fatalf("unable to init ws2812: %v", err)
+	}
+
+	s.SetColor(ColorRed)
+	if s.Color() != ColorRed {
+		t.Errorf("%T.Color(): %v, wants %v", s, s.Color(), ColorRed)
+	}
+
+	if len(port.Ops) != 2 {
+		t.Fatalf("%v spi writes, wants %v", len(port.Ops), 2)
+	}
+	expectedOff := ws2812Frame(ws2812Zero, ws2812Zero, ws2812Zero, ws2812Zero, ws2812Zero, ws2812Zero)
+	if !bytes.Equal(port.Ops[0].W, expectedOff) {
+		t.Errorf("init frame: %#v, wants %#v", port.Ops[0].W, expectedOff)
+	}
+	// GRB order
+	expectedRed := ws2812Frame(ws2812Zero, ws2812Full, ws2812Zero, ws2812Zero, ws2812Full, ws2812Zero)
+	if !bytes.Equal(port.Ops[1].W, expectedRed) {
+		t.Errorf("red frame: %#v, wants %#v", port.Ops[1].W, expectedRed)
+	}
+}
+
+func TestWS2812_SetBlink(t *testing.T) {
+	port := spitest.Record{}
+	clk := clock.NewFake(time.Now())
+	s, err := NewWS2812(&port, 1, WithStripClock(clk))
+	if err != nil {
+		t.Fatalf("unable to init ws2812: %v", err)
+	}
+	lastFrame := func() []byte {
+		port.Lock()
+		defer port.Unlock()
+		return port.Ops[len(port.Ops)-1].W
+	}
+
+	s.SetColor(ColorRed)
+	s.SetBlink(2)
+	clk.BlockUntil(1)
+	clk.Advance(500 * time.Millisecond)
+	clk.BlockUntil(1)
+	if frame, expected := lastFrame(), ws2812Frame(ws2812Zero, ws2812Zero, ws2812Zero); !bytes.Equal(frame, expected) {
+		t.Errorf("frame during off phase: %#v, wants %#v", frame, expected)
+	}
+
+	// Color set during off phase is displayed at next on phase
+	s.SetColor(ColorBlue)
+	if frame, expected := lastFrame(), ws2812Frame(ws2812Zero, ws2812Zero, ws2812Zero); !bytes.Equal(frame, expected) {
+		t.Errorf("frame after color change during off phase: %#v, wants %#v", frame, expected)
+	}
+	clk.Advance(500 * time.Millisecond)
+	clk.BlockUntil(1)
+	if frame, expected := lastFrame(), ws2812Frame(ws2812Zero, ws2812Zero, ws2812Full); !bytes.Equal(frame, expected) {
+		t.Errorf("frame during on phase: %#v, wants %#v", frame, expected)
+	}
+
+	s.SetBlink(0)
+	s.wait()
+	if frame, expected := lastFrame(), ws2812Frame(ws2812Zero, ws2812Zero, ws2812Full); !bytes.Equal(frame, expected) {
+		t.Errorf("frame after blink stop: %#v, wants %#v", frame, expected)
+	}
+	if s.Color() != ColorBlue {
+		t.Errorf("%T.Color(): %v, wants %v", s, s.Color(), ColorBlue)
+	}
+}
+
+func TestWS2812_SetPixel(t *testing.T) {
+	port := spitest.Record{}
+	s, err := NewWS2812(&port, 2)
+	if err != nil {
+		t.Fatalf("unable to init ws2812: %v", err)
+	}
+
+	if err := s.SetPixel(1, ColorBlue); err != nil {
+		t.Errorf("unable to set pixel: %v", err)
+	}
+	expected := ws2812Frame(ws2812Zero, ws2812Zero, ws2812Zero, ws2812Zero, ws2812Zero, ws2812Full)
+	if last := port.Ops[len(port.Ops)-1].W; !bytes.Equal(last, expected) {
+		t.Errorf("frame: %#v, wants %#v", last, expected)
+	}
+	if pixels := s.Pixels(); pixels[0] != ColorBlack || pixels[1] != ColorBlue {
+		t.Errorf("%T.Pixels(): %v, wants %v", s, pixels, []Color{ColorBlack, ColorBlue})
+	}
+
+	if err := s.SetPixel(2, ColorBlue); err == nil {
+		t.Errorf("SetPixel must fail with out of range index")
+	}
+}
+
+func TestNewWS2812_InvalidPixelCount(t *testing.T) {
+	if _, err := NewWS2812(&spitest.Record{}, 0); err == nil {
+		t.Errorf("NewWS2812 must fail with invalid pixel count")
+	}
+}
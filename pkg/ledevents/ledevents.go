@@ -0,0 +1,4 @@
+// Package ledevents contains messages published by rc-led, generated from ledstate.proto
+package ledevents
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative ledstate.proto
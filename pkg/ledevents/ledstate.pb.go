@@ -0,0 +1,238 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: ledstate.proto
+
+package ledevents
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// LedStateMessage is published by rc-led on -mqtt-topic-led-state each time led output changes.
+// Durations are in milliseconds, a blink with on or off at 0 means steady light.
+type LedStateMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Red        int32 `protobuf:"varint,1,opt,name=red,proto3" json:"red,omitempty"`
+	Green      int32 `protobuf:"varint,2,opt,name=green,proto3" json:"green,omitempty"`
+	Blue       int32 `protobuf:"varint,3,opt,name=blue,proto3" json:"blue,omitempty"`
+	BlinkOnMs  int64 `protobuf:"varint,4,opt,name=blink_on_ms,json=blinkOnMs,proto3" json:"blink_on_ms,omitempty"`
+	BlinkOffMs int64 `protobuf:"varint,5,opt,name=blink_off_ms,json=blinkOffMs,proto3" json:"blink_off_ms,omitempty"`
+	// when blink_count > 0, pulses are grouped by bursts of blink_count separated by blink_pause_ms
+	BlinkCount   int32 `protobuf:"varint,6,opt,name=blink_count,json=blinkCount,proto3" json:"blink_count,omitempty"`
+	BlinkPauseMs int64 `protobuf:"varint,7,opt,name=blink_pause_ms,json=blinkPauseMs,proto3" json:"blink_pause_ms,omitempty"`
+	// mode is the led mode of car state strategy (brake, speedzone, rules, ...)
+	Mode string `protobuf:"bytes,8,opt,name=mode,proto3" json:"mode,omitempty"`
+	// source is the layer that gives color, "state" for car state
+	Source string `protobuf:"bytes,9,opt,name=source,proto3" json:"source,omitempty"`
+	// reason describes what produced color (ex: rule name)
+	Reason string `protobuf:"bytes,10,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *LedStateMessage) Reset() {
+	*x = LedStateMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ledstate_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LedStateMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LedStateMessage) ProtoMessage() {}
+
+func (x *LedStateMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_ledstate_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LedStateMessage.ProtoReflect.Descriptor instead.
+func (*LedStateMessage) Descriptor() ([]byte, []int) {
+	return file_ledstate_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LedStateMessage) GetRed() int32 {
+	if x != nil {
+		return x.Red
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetGreen() int32 {
+	if x != nil {
+		return x.Green
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetBlue() int32 {
+	if x != nil {
+		return x.Blue
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetBlinkOnMs() int64 {
+	if x != nil {
+		return x.BlinkOnMs
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetBlinkOffMs() int64 {
+	if x != nil {
+		return x.BlinkOffMs
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetBlinkCount() int32 {
+	if x != nil {
+		return x.BlinkCount
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetBlinkPauseMs() int64 {
+	if x != nil {
+		return x.BlinkPauseMs
+	}
+	return 0
+}
+
+func (x *LedStateMessage) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *LedStateMessage) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *LedStateMessage) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+var File_ledstate_proto protoreflect.FileDescriptor
+
+var file_ledstate_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x6c, 0x65, 0x64, 0x73, 0x74, 0x61, 0x74, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0b, 0x72, 0x6f, 0x62, 0x6f, 0x63, 0x61, 0x72, 0x2e, 0x6c, 0x65, 0x64, 0x22, 0x9a, 0x02,
+	0x0a, 0x0f, 0x4c, 0x65, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03,
+	0x72, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x65, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x67, 0x72, 0x65, 0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x75,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x62, 0x6c, 0x75, 0x65, 0x12, 0x1e, 0x0a,
+	0x0b, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x5f, 0x6f, 0x6e, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x09, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x4f, 0x6e, 0x4d, 0x73, 0x12, 0x20, 0x0a,
+	0x0c, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x5f, 0x6f, 0x66, 0x66, 0x5f, 0x6d, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0a, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x4f, 0x66, 0x66, 0x4d, 0x73, 0x12,
+	0x1f, 0x0a, 0x0b, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x12, 0x24, 0x0a, 0x0e, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x5f, 0x70, 0x61, 0x75, 0x73, 0x65, 0x5f,
+	0x6d, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x62, 0x6c, 0x69, 0x6e, 0x6b, 0x50,
+	0x61, 0x75, 0x73, 0x65, 0x4d, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x79, 0x72, 0x69, 0x6c, 0x69, 0x78,
+	0x2f, 0x72, 0x6f, 0x62, 0x6f, 0x63, 0x61, 0x72, 0x2d, 0x6c, 0x65, 0x64, 0x2f, 0x70, 0x6b, 0x67,
+	0x2f, 0x6c, 0x65, 0x64, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_ledstate_proto_rawDescOnce sync.Once
+	file_ledstate_proto_rawDescData = file_ledstate_proto_rawDesc
+)
+
+func file_ledstate_proto_rawDescGZIP() []byte {
+	file_ledstate_proto_rawDescOnce.Do(func() {
+		file_ledstate_proto_rawDescData = protoimpl.X.CompressGZIP(file_ledstate_proto_rawDescData)
+	})
+	return file_ledstate_proto_rawDescData
+}
+
+var file_ledstate_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_ledstate_proto_goTypes = []interface{}{
+	(*LedStateMessage)(nil), // 0: robocar.led.LedStateMessage
+}
+var file_ledstate_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_ledstate_proto_init() }
+func file_ledstate_proto_init() {
+	if File_ledstate_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_ledstate_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LedStateMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_ledstate_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ledstate_proto_goTypes,
+		DependencyIndexes: file_ledstate_proto_depIdxs,
+		MessageInfos:      file_ledstate_proto_msgTypes,
+	}.Build()
+	File_ledstate_proto = out.File
+	file_ledstate_proto_rawDesc = nil
+	file_ledstate_proto_goTypes = nil
+	file_ledstate_proto_depIdxs = nil
+}
@@ -0,0 +1,79 @@
+package part
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"strings"
+	"time"
+)
+
+// Animation kinds
+const (
+	// AnimationFade transitions to color during Duration
+	AnimationFade = "fade"
+	// AnimationBreathe fades color in and out forever, Duration is the period of a full cycle
+	AnimationBreathe = "breathe"
+)
+
+// Animation is played by led animator to display a color, zero value displays color as is
+type Animation struct {
+	Kind     string
+	Duration time.Duration
+}
+
+// ParseAnimation reads an animation written as kind and duration ("breathe 2s", "fade 500ms"). Empty
+// string and "none" disable animation.
+func ParseAnimation(s string) (Animation, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 || len(fields) == 1 && fields[0] == "none" {
+		return Animation{}, nil
+	}
+	if len(fields) != 2 {
+		return Animation{}, fmt.Errorf("invalid animation '%v', must be a kind and a duration", s)
+	}
+	kind := fields[0]
+	if kind != AnimationFade && kind != AnimationBreathe {
+		return Animation{}, fmt.Errorf("unknown animation '%v'", fields[0])
+	}
+	d, err := time.ParseDuration(fields[1])
+	if err != nil {
+		return Animation{}, fmt.Errorf("invalid animation duration: %v", err)
+	}
+	if d <= 0 {
+		return Animation{}, fmt.Errorf("invalid animation duration %v, must be positive", d)
+	}
+	return Animation{Kind: kind, Duration: d}, nil
+}
+
+func (a Animation) Enabled() bool {
+	return a.Kind != ""
+}
+
+// String formats animation in a form read by ParseAnimation
+func (a Animation) String() string {
+	if !a.Enabled() {
+		return "none"
+	}
+	return fmt.Sprintf("%v %v", a.Kind, a.Duration)
+}
+
+func (a Animation) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Animation) UnmarshalText(text []byte) error {
+	anim, err := ParseAnimation(string(text))
+	if err != nil {
+		return err
+	}
+	*a = anim
+	return nil
+}
+
+// play returns led animation that displays color
+func (a Animation) play(color led.Color) led.Animation {
+	if a.Kind == AnimationBreathe {
+		return led.Breathe(color, a.Duration)
+	}
+	return led.Fade(color, a.Duration, led.EaseInOut)
+}
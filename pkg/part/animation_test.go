@@ -0,0 +1,81 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"testing"
+	"time"
+)
+
+func TestParseAnimation(t *testing.T) {
+	cases := []struct {
+		text      string
+		animation Animation
+	}{
+		{"", Animation{}},
+		{"none", Animation{}},
+		{"fade 500ms", Animation{Kind: AnimationFade, Duration: 500 * time.Millisecond}},
+		{" Breathe  2s ", Animation{Kind: AnimationBreathe, Duration: 2 * time.Second}},
+	}
+	for _, c := range cases {
+		a, err := ParseAnimation(c.text)
+		if err != nil {
+			t.Errorf("unable to parse '%v': %v", c.text, err)
+			continue
+		}
+		if a != c.animation {
+			t.Errorf("animation of '%v': %+v, wants %+v", c.text, a, c.animation)
+		}
+		if b, err := ParseAnimation(a.String()); err != nil || b != a {
+			t.Errorf("'%v' formatted as '%v' read as (%+v, %v)", c.text, a, b, err)
+		}
+	}
+
+	for _, invalid := range []string{"breathe", "pulse 1s", "fade soon", "fade -1s", "fade 0s", "fade 1s 2s"} {
+		if _, err := ParseAnimation(invalid); err == nil {
+			t.Errorf("no error for '%v'", invalid)
+		}
+	}
+}
+
+func TestLedPart_Animation(t *testing.T) {
+	l := fakeLed{}
+	clk := clock.NewFake(time.Now())
+	client := fakeClient{}
+	p := NewPart(&client, &l, "drive", "record", "speedzone", "throttle", LedModeRules, WithClock(clk), WithRules(Rules{
+		{DriveModes: []string{"user"}, Color: "#FF0000", Animation: &Animation{Kind: AnimationFade, Duration: time.Second}},
+		{DriveModes: []string{"pilot"}, Color: "#0000FF", Animation: &Animation{Kind: AnimationBreathe, Duration: 2 * time.Second}},
+		{Color: "#00FF00"},
+	}))
+	defer p.Stop()
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	p.animator.Wait()
+	if l.color != led.ColorRed {
+		t.Errorf("color after fade: %v, wants %v", l.color, led.ColorRed)
+	}
+
+	// Breathe reaches color at half period, then fades out
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	clk.BlockUntil(1)
+	if l.color != led.ColorBlue {
+		t.Errorf("color at half breathe period: %v, wants %v", l.color, led.ColorBlue)
+	}
+	clk.Advance(500 * time.Millisecond)
+	clk.BlockUntil(1)
+	if l.color == led.ColorBlue || l.color == led.ColorBlack {
+		t.Errorf("color while breathing out: %v, wants color between %v and %v", l.color, led.ColorBlue, led.ColorBlack)
+	}
+
+	// Rule without animation stops breathe
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_COPILOT}))
+	if l.color != led.ColorGreen {
+		t.Errorf("color without animation: %v, wants %v", l.color, led.ColorGreen)
+	}
+}
@@ -0,0 +1,136 @@
+package part
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBrakeHysteresis is the throttle margin needed to release a brake level
+	DefaultBrakeHysteresis = 0.02
+	// DefaultBrakeMinHold is the duration after which a brake level follows throttle without hysteresis
+	DefaultBrakeMinHold = 500 * time.Millisecond
+)
+
+// DefaultBrakeThresholds are throttle values that enter each brake level of palette
+var DefaultBrakeThresholds = []float32{-0.05, -0.25, -0.5, -0.75}
+
+// BrakeLevel is a step of brake ladder, Color is displayed when throttle <= Threshold
+type BrakeLevel struct {
+	Threshold float32
+	Color     led.Color
+}
+
+// BrakeLevels builds brake ladder from default thresholds and brake colors of palette
+func (p Palette) BrakeLevels() []BrakeLevel {
+	colors := []led.Color{p.BrakeLight, p.BrakeMedium, p.BrakeHigh, p.BrakeMax}
+	levels := make([]BrakeLevel, len(DefaultBrakeThresholds))
+	for i, t := range DefaultBrakeThresholds {
+		levels[i] = BrakeLevel{Threshold: t, Color: colors[i]}
+	}
+	return levels
+}
+
+// ParseBrakeLevels reads a comma separated list of threshold=color (ex: "-0.1=white,-0.5=red")
+func ParseBrakeLevels(s string) ([]BrakeLevel, error) {
+	var levels []BrakeLevel
+	for _, l := range led.SplitList(s) {
+		threshold, color, ok := strings.Cut(l, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid brake level '%v', must be threshold=color", l)
+		}
+		t, err := strconv.ParseFloat(strings.TrimSpace(threshold), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold of brake level '%v': %v", l, err)
+		}
+		c, err := led.ParseColor(color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color of brake level '%v': %v", l, err)
+		}
+		levels = append(levels, BrakeLevel{Threshold: float32(t), Color: c})
+	}
+	return levels, ValidateBrakeLevels(levels)
+}
+
+// ValidateBrakeLevels checks levels are sorted from lightest to hardest brake
+func ValidateBrakeLevels(levels []BrakeLevel) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("brake ladder without level")
+	}
+	for i := 1; i < len(levels); i++ {
+		if levels[i].Threshold >= levels[i-1].Threshold {
+			return fmt.Errorf("brake level thresholds must decrease: %v after %v", levels[i].Threshold, levels[i-1].Threshold)
+		}
+	}
+	return nil
+}
+
+// brakeLadder selects brake level from throttle. A harder level is entered as soon as its threshold
+// is crossed, but a level is only released once throttle exceeds its threshold by hysteresis, or
+// without margin once level has been held for minHold.
+type brakeLadder struct {
+	levels     []BrakeLevel
+	hysteresis float32
+	minHold    time.Duration
+	clock      clock.Clock
+
+	// level is index of current level, -1 if not braking
+	level int
+	since time.Time
+}
+
+func newBrakeLadder(levels []BrakeLevel, hysteresis float32, minHold time.Duration, clk clock.Clock) *brakeLadder {
+	sorted := make([]BrakeLevel, len(levels))
+	copy(sorted, levels)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Threshold > sorted[j].Threshold
+	})
+	return &brakeLadder{
+		levels:     sorted,
+		hysteresis: hysteresis,
+		minHold:    minHold,
+		clock:      clk,
+		level:      -1,
+	}
+}
+
+// update computes brake level for throttle and returns its color, false if not braking
+func (b *brakeLadder) update(throttle float32) (led.Color, bool) {
+	raw := b.levelOf(throttle)
+	switch {
+	case raw > b.level:
+		b.setLevel(raw)
+	case raw < b.level:
+		if released := b.levelOf(throttle - b.hysteresis); released < b.level {
+			b.setLevel(released)
+		} else if b.minHold > 0 && b.clock.Now().Sub(b.since) >= b.minHold {
+			b.setLevel(raw)
+		}
+	}
+
+	if b.level < 0 {
+		return led.Color{}, false
+	}
+	return b.levels[b.level].Color, true
+}
+
+func (b *brakeLadder) setLevel(level int) {
+	b.level = level
+	b.since = b.clock.Now()
+}
+
+// levelOf returns index of hardest level crossed by throttle, -1 if none
+func (b *brakeLadder) levelOf(throttle float32) int {
+	level := -1
+	for i, l := range b.levels {
+		if throttle <= l.Threshold {
+			level = i
+		}
+	}
+	return level
+}
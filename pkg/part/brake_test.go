@@ -0,0 +1,102 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"testing"
+	"time"
+)
+
+func TestBrakeLadder_Hysteresis(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	b := newBrakeLadder(DefaultPalette.BrakeLevels(), 0.05, 0, clk)
+
+	steps := []struct {
+		throttle float32
+		color    led.Color
+		braking  bool
+	}{
+		{0, led.Color{}, false},
+		{-0.26, led.ColorYellow, true},
+		// Hover around -0.25 boundary
+		{-0.24, led.ColorYellow, true},
+		{-0.26, led.ColorYellow, true},
+		{-0.21, led.ColorYellow, true},
+		// Harder level entered immediately
+		{-0.5, led.ColorRed, true},
+		{-0.46, led.ColorRed, true},
+		// Released with margin
+		{-0.44, led.ColorYellow, true},
+		{-0.1, led.ColorWhite, true},
+		{-0.01, led.ColorWhite, true},
+		{0.1, led.Color{}, false},
+	}
+	for i, s := range steps {
+		color, braking := b.update(s.throttle)
+		if braking != s.braking || color != s.color {
+			t.Errorf("step %v, throttle %v: (%v, %v), wants (%v, %v)", i, s.throttle, color, braking, s.color, s.braking)
+		}
+	}
+}
+
+func TestBrakeLadder_MinHold(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	b := newBrakeLadder(DefaultPalette.BrakeLevels(), 0.05, 200*time.Millisecond, clk)
+
+	b.update(-0.3)
+	clk.Advance(100 * time.Millisecond)
+	if color, _ := b.update(-0.24); color != led.ColorYellow {
+		t.Errorf("level released before min hold: %v", color)
+	}
+	clk.Advance(100 * time.Millisecond)
+	if color, _ := b.update(-0.24); color != led.ColorWhite {
+		t.Errorf("level not released after min hold: %v, wants %v", color, led.ColorWhite)
+	}
+	if color, _ := b.update(-0.26); color != led.ColorYellow {
+		t.Errorf("harder level not entered: %v, wants %v", color, led.ColorYellow)
+	}
+}
+
+func TestParseBrakeLevels(t *testing.T) {
+	levels, err := ParseBrakeLevels("-0.1=white, -0.5=rgb(255, 0, 0)")
+	if err != nil {
+		t.Fatalf("unable to parse brake levels: %v", err)
+	}
+	expected := []BrakeLevel{{-0.1, led.ColorWhite}, {-0.5, led.ColorRed}}
+	if len(levels) != len(expected) {
+		t.Fatalf("%v levels, wants %v", levels, expected)
+	}
+	for i := range expected {
+		if levels[i] != expected[i] {
+			t.Errorf("level %v: %v, wants %v", i, levels[i], expected[i])
+		}
+	}
+
+	for _, s := range []string{"", "-0.1", "x=red", "-0.1=pink", "-0.5=red,-0.1=white"} {
+		if _, err := ParseBrakeLevels(s); err == nil {
+			t.Errorf("ParseBrakeLevels(%q) must fail", s)
+		}
+	}
+}
+
+func TestLedPart_BrakeLevels(t *testing.T) {
+	l := led.NewSimLed()
+	p := NewPart(nil, l, "drive", "record", "speedzone", "throttle", LedModeBrake,
+		WithBrakeLevels([]BrakeLevel{{-0.1, led.ColorWhite}, {-0.5, led.ColorRed}}),
+		WithBrakeHysteresis(0.05, time.Hour),
+	)
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+
+	for _, throttle := range []float32{-0.5, -0.47, -0.55, -0.46} {
+		p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: throttle}))
+		if l.Color() != led.ColorRed {
+			t.Errorf("throttle %v: %v, wants %v", throttle, l.Color(), led.ColorRed)
+		}
+	}
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: 0}))
+	if l.Color() != led.ColorBlue {
+		t.Errorf("color after brake: %v, wants %v", l.Color(), led.ColorBlue)
+	}
+}
@@ -0,0 +1,105 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"time"
+)
+
+// DefaultDecelerationWindow is the duration over which throttle drops are measured
+const DefaultDecelerationWindow = 200 * time.Millisecond
+
+type throttleSample struct {
+	time     time.Time
+	throttle float32
+}
+
+// brakeLight extends brake ladder like a car brake light: braking is also detected when throttle
+// drops by decelerationDrop within decelerationWindow, and brake color is held during hold after
+// braking ends
+type brakeLight struct {
+	hold               time.Duration
+	decelerationDrop   float32
+	decelerationWindow time.Duration
+	clock              clock.Clock
+
+	samples   []throttleSample
+	color     led.Color
+	holdUntil time.Time
+}
+
+func newBrakeLight(hold time.Duration, drop float32, window time.Duration, clk clock.Clock) *brakeLight {
+	return &brakeLight{
+		hold:               hold,
+		decelerationDrop:   drop,
+		decelerationWindow: window,
+		clock:              clk,
+	}
+}
+
+func (b *brakeLight) enabled() bool {
+	return b.hold > 0 || b.decelerationDrop > 0
+}
+
+// addThrottle records throttle to detect deceleration
+func (b *brakeLight) addThrottle(throttle float32) {
+	if b.decelerationDrop <= 0 {
+		return
+	}
+	now := b.clock.Now()
+	b.samples = append(b.samples, throttleSample{time: now, throttle: throttle})
+	b.prune(now)
+}
+
+// prune drops samples out of window, last sample is always kept
+func (b *brakeLight) prune(now time.Time) {
+	first := 0
+	for first < len(b.samples)-1 && now.Sub(b.samples[first].time) >= b.decelerationWindow {
+		first += 1
+	}
+	b.samples = b.samples[first:]
+}
+
+// decelerating returns true if last throttle is lower than a throttle of window by decelerationDrop,
+// expire is the duration before oldest sample leaves window
+func (b *brakeLight) decelerating(now time.Time) (decelerating bool, expire time.Duration) {
+	if b.decelerationDrop <= 0 {
+		return false, 0
+	}
+	b.prune(now)
+	if len(b.samples) < 2 {
+		return false, 0
+	}
+	last := b.samples[len(b.samples)-1]
+	for _, s := range b.samples[:len(b.samples)-1] {
+		if s.throttle-last.throttle >= b.decelerationDrop {
+			return true, b.samples[0].time.Add(b.decelerationWindow).Sub(now)
+		}
+	}
+	return false, 0
+}
+
+// update returns color to display from brake ladder result. While braking or during hold, it
+// returns brake color and true. remaining is the duration after which output must be computed again
+// without new throttle: time before oldest sample leaves window while decelerating, hold left once
+// braking ended
+func (b *brakeLight) update(color led.Color, braking bool, decelerationColor led.Color) (c led.Color, brake bool, remaining time.Duration) {
+	now := b.clock.Now()
+	if !braking {
+		if decelerating, expire := b.decelerating(now); decelerating {
+			color = decelerationColor
+			braking = true
+			remaining = expire
+		}
+	}
+
+	if braking {
+		b.color = color
+		b.holdUntil = now.Add(b.hold)
+		return color, true, remaining
+	}
+	if now.Before(b.holdUntil) {
+		return b.color, true, b.holdUntil.Sub(now)
+	}
+	return led.Color{}, false, 0
+}
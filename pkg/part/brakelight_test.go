@@ -0,0 +1,129 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"testing"
+	"time"
+)
+
+func TestBrakeLight_Hold(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	b := newBrakeLight(time.Second, 0, DefaultDecelerationWindow, clk)
+
+	if c, brake, _ := b.update(led.ColorRed, true, led.ColorWhite); !brake || c != led.ColorRed {
+		t.Errorf("braking: (%v, %v), wants (%v, true)", c, brake, led.ColorRed)
+	}
+	clk.Advance(400 * time.Millisecond)
+	c, brake, remaining := b.update(led.Color{}, false, led.ColorWhite)
+	if !brake || c != led.ColorRed {
+		t.Errorf("hold: (%v, %v), wants (%v, true)", c, brake, led.ColorRed)
+	}
+	if remaining != 600*time.Millisecond {
+		t.Errorf("remaining hold: %v, wants %v", remaining, 600*time.Millisecond)
+	}
+	clk.Advance(600 * time.Millisecond)
+	if _, brake, _ := b.update(led.Color{}, false, led.ColorWhite); brake {
+		t.Errorf("brake still displayed after hold")
+	}
+}
+
+func TestBrakeLight_Deceleration(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	b := newBrakeLight(0, 0.3, 200*time.Millisecond, clk)
+
+	steps := []struct {
+		elapsed  time.Duration
+		throttle float32
+		brake    bool
+		refresh  time.Duration
+	}{
+		{0, 0.8, false, 0},
+		{50 * time.Millisecond, 0.7, false, 0},
+		// Refresh when 0.8 sample leaves window
+		{50 * time.Millisecond, 0.4, true, 100 * time.Millisecond},
+		{50 * time.Millisecond, 0.4, true, 50 * time.Millisecond},
+		// 0.8 and 0.7 samples out of window
+		{200 * time.Millisecond, 0.4, false, 0},
+		// Slow decrease
+		{150 * time.Millisecond, 0.2, false, 0},
+		{150 * time.Millisecond, 0., false, 0},
+	}
+	for i, s := range steps {
+		clk.Advance(s.elapsed)
+		b.addThrottle(s.throttle)
+		c, brake, refresh := b.update(led.Color{}, false, led.ColorWhite)
+		if brake != s.brake {
+			t.Errorf("step %v, throttle %v: brake=%v, wants %v", i, s.throttle, brake, s.brake)
+		}
+		if refresh != s.refresh {
+			t.Errorf("step %v: refresh %v, wants %v", i, refresh, s.refresh)
+		}
+		if brake && c != led.ColorWhite {
+			t.Errorf("step %v: deceleration color %v, wants %v", i, c, led.ColorWhite)
+		}
+	}
+}
+
+func TestLedPart_BrakeHold(t *testing.T) {
+	l := led.NewSimLed()
+	clk := clock.NewFake(time.Now())
+	client := fakeClient{}
+	p := NewPart(&client, l, "drive", "record", "speedzone", "throttle", LedModeBrake,
+		WithClock(clk), WithBrakeHold(time.Second))
+	defer p.Stop()
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: -0.3}))
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: 0.2}))
+	if l.Color() != led.ColorYellow {
+		t.Errorf("color after brake: %v, wants brake color %v", l.Color(), led.ColorYellow)
+	}
+
+	clk.BlockUntil(1)
+	clk.Advance(999 * time.Millisecond)
+	if l.Color() != led.ColorYellow {
+		t.Errorf("color during hold: %v, wants brake color %v", l.Color(), led.ColorYellow)
+	}
+
+	// Color is refreshed at end of hold without new message
+	clk.Advance(time.Millisecond)
+	if l.Color() != led.ColorBlue {
+		t.Errorf("color after hold: %v, wants %v", l.Color(), led.ColorBlue)
+	}
+}
+
+func TestLedPart_DecelerationBrake(t *testing.T) {
+	l := led.NewSimLed()
+	clk := clock.NewFake(time.Now())
+	client := fakeClient{}
+	p := NewPart(&client, l, "drive", "record", "speedzone", "throttle", LedModeBrake,
+		WithClock(clk), WithDecelerationBrake(0.3, 200*time.Millisecond))
+	defer p.Stop()
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: 0.8}))
+	clk.Advance(100 * time.Millisecond)
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: 0.3}))
+	if l.Color() != led.ColorWhite {
+		t.Errorf("color on throttle drop: %v, wants %v", l.Color(), led.ColorWhite)
+	}
+
+	// Brake is cleared when throttle drop leaves window without new message
+	clk.Advance(99 * time.Millisecond)
+	if l.Color() != led.ColorWhite {
+		t.Errorf("color in deceleration window: %v, wants %v", l.Color(), led.ColorWhite)
+	}
+	clk.Advance(time.Millisecond)
+	if l.Color() != led.ColorBlue {
+		t.Errorf("color after deceleration window: %v, wants %v", l.Color(), led.ColorBlue)
+	}
+
+	clk.Advance(300 * time.Millisecond)
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: 0.3}))
+	if l.Color() != led.ColorBlue {
+		t.Errorf("color with steady throttle: %v, wants %v", l.Color(), led.ColorBlue)
+	}
+}
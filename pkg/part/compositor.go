@@ -0,0 +1,222 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Priorities of layers submitted by LedPart sources, a layer hides layers with a lower priority
+const (
+	PriorityState    = 0
+	PriorityRecord   = 100
+	PriorityOverride = 300
+)
+
+// SourceState is the source of layer computed by strategy from car state
+const SourceState = "state"
+
+// SourceRecord is the source of layer that blinks led while video is recorded
+const SourceRecord = "record"
+
+// Layer is led output wanted by a source. A nil Color or Blink is transparent: it's taken from the
+// highest priority layer below that sets it.
+type Layer struct {
+	Priority int
+	Color    *led.Color
+	Blink    *led.BlinkPattern
+	// Animation is played to display Color, it's ignored without Color
+	Animation Animation
+	// TTL removes layer once elapsed, layer is kept until cleared if 0
+	TTL time.Duration
+	// Reason describes what produced layer (ex: rule name)
+	Reason string
+}
+
+// Composite is led output merged from layers
+type Composite struct {
+	Color     led.Color
+	Animation Animation
+	Blink     led.BlinkPattern
+	// Animation, Source and Reason come from layer that gives color, BlinkSource from layer that gives
+	// blink
+	Source      string
+	Reason      string
+	BlinkSource string
+}
+
+type activeLayer struct {
+	Layer
+	source  string
+	expires time.Time
+	// seq orders layers with same priority, last set wins
+	seq uint64
+}
+
+// Compositor merges layers of many sources and renders result each time it changes: color comes
+// from the highest priority layer with a color, blink from the highest priority layer with a
+// blink. Led is black and steady without layer.
+type Compositor struct {
+	render func(c Composite)
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	layers   map[string]activeLayer
+	seq      uint64
+	rendered bool
+	output   Composite
+	// timer removes expired layers
+	timer  clock.Timer
+	closed bool
+}
+
+func NewCompositor(render func(c Composite), clk clock.Clock) *Compositor {
+	return &Compositor{
+		render: render,
+		clock:  clk,
+		layers: make(map[string]activeLayer),
+	}
+}
+
+// Set replaces layer of source and renders result
+func (c *Compositor) Set(source string, l Layer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq += 1
+	a := activeLayer{Layer: l, source: source, seq: c.seq}
+	if l.TTL > 0 {
+		a.expires = c.clock.Now().Add(l.TTL)
+	}
+	c.layers[source] = a
+	c.update()
+}
+
+// Clear removes layer of source and renders result
+func (c *Compositor) Clear(source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.layers[source]; !ok {
+		return
+	}
+	delete(c.layers, source)
+	c.update()
+}
+
+// Sources lists sources with an active layer, from highest priority
+func (c *Compositor) Sources() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	layers := c.sorted()
+	sources := make([]string, len(layers))
+	for i, l := range layers {
+		sources[i] = l.source
+	}
+	return sources
+}
+
+// Output returns last rendered output
+func (c *Compositor) Output() Composite {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.output
+}
+
+// Close stops expiry of layers
+func (c *Compositor) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// update drops expired layers, renders result if changed and schedules next expiry. Must be called
+// with mu locked
+func (c *Compositor) update() {
+	now := c.clock.Now()
+	var next time.Time
+	for source, l := range c.layers {
+		if l.expires.IsZero() {
+			continue
+		}
+		if !now.Before(l.expires) {
+			delete(c.layers, source)
+			continue
+		}
+		if next.IsZero() || l.expires.Before(next) {
+			next = l.expires
+		}
+	}
+
+	output := compose(c.sorted())
+	if !c.rendered || output != c.output {
+		c.rendered = true
+		c.output = output
+		c.render(output)
+	}
+
+	if !next.IsZero() {
+		c.expireAfter(next.Sub(now))
+	}
+}
+
+// sorted returns layers from highest priority, must be called with mu locked
+func (c *Compositor) sorted() []activeLayer {
+	layers := make([]activeLayer, 0, len(c.layers))
+	for _, l := range c.layers {
+		layers = append(layers, l)
+	}
+	sort.Slice(layers, func(i, j int) bool {
+		if layers[i].Priority != layers[j].Priority {
+			return layers[i].Priority > layers[j].Priority
+		}
+		return layers[i].seq > layers[j].seq
+	})
+	return layers
+}
+
+func compose(layers []activeLayer) Composite {
+	c := Composite{Color: led.ColorBlack}
+	colorFound, blinkFound := false, false
+	for _, l := range layers {
+		if !colorFound && l.Color != nil {
+			colorFound = true
+			c.Color = *l.Color
+			c.Animation = l.Animation
+			c.Source = l.source
+			c.Reason = l.Reason
+		}
+		if !blinkFound && l.Blink != nil {
+			blinkFound = true
+			c.Blink = *l.Blink
+			c.BlinkSource = l.source
+		}
+	}
+	return c
+}
+
+// expireAfter updates layers once d elapsed, must be called with mu locked
+func (c *Compositor) expireAfter(d time.Duration) {
+	if c.closed {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Reset(d)
+		return
+	}
+	c.timer = c.clock.AfterFunc(d, c.expire)
+}
+
+// expire removes expired layers, until compositor is closed
+func (c *Compositor) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.update()
+}
@@ -0,0 +1,204 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type renderRecorder struct {
+	mu     sync.Mutex
+	colors []led.Color
+	blinks []led.BlinkPattern
+}
+
+func (r *renderRecorder) render(c Composite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.colors = append(r.colors, c.Color)
+	r.blinks = append(r.blinks, c.Blink)
+}
+
+func (r *renderRecorder) renders() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.colors)
+}
+
+func colorRef(c led.Color) *led.Color {
+	return &c
+}
+
+func blinkRef(b led.BlinkPattern) *led.BlinkPattern {
+	return &b
+}
+
+func TestCompositor(t *testing.T) {
+	r := renderRecorder{}
+	c := NewCompositor(r.render, clock.NewFake(time.Now()))
+	defer c.Close()
+
+	fast := led.BlinkFrequency(4)
+	steps := []struct {
+		name   string
+		apply  func()
+		color  led.Color
+		blink  led.BlinkPattern
+		render bool
+	}{
+		{"state", func() {
+			c.Set(SourceState, Layer{Priority: PriorityState, Color: colorRef(led.ColorBlue), Blink: blinkRef(led.BlinkPattern{})})
+		}, led.ColorBlue, led.BlinkPattern{}, true},
+		{"blink only layer keeps color", func() {
+			c.Set("record", Layer{Priority: 10, Blink: &recordBlinkPattern})
+		}, led.ColorBlue, recordBlinkPattern, true},
+		{"higher layer", func() {
+			c.Set("fault", Layer{Priority: 100, Color: colorRef(led.ColorRed)})
+		}, led.ColorRed, recordBlinkPattern, true},
+		{"lower layer hidden", func() {
+			c.Set(SourceState, Layer{Priority: PriorityState, Color: colorRef(led.ColorGreen)})
+		}, led.ColorRed, recordBlinkPattern, false},
+		{"steady blink hides lower blink", func() {
+			c.Set("notification", Layer{Priority: 200, Blink: blinkRef(led.BlinkPattern{})})
+		}, led.ColorRed, led.BlinkPattern{}, true},
+		{"same priority, last set wins", func() {
+			c.Set("other fault", Layer{Priority: 100, Color: colorRef(led.ColorWhite), Blink: &fast})
+		}, led.ColorWhite, led.BlinkPattern{}, true},
+		{"clear", func() {
+			c.Clear("notification")
+			c.Clear("other fault")
+			c.Clear("fault")
+		}, led.ColorGreen, recordBlinkPattern, true},
+		{"clear unknown source", func() {
+			c.Clear("unknown")
+		}, led.ColorGreen, recordBlinkPattern, false},
+	}
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			before := r.renders()
+			s.apply()
+			if out := c.Output(); out.Color != s.color || out.Blink != s.blink {
+				t.Errorf("output: (%v, %v), wants (%v, %v)", out.Color, out.Blink, s.color, s.blink)
+			}
+			if rendered := r.renders() > before; rendered != s.render {
+				t.Errorf("rendered: %v, wants %v", rendered, s.render)
+			}
+			if r.renders() > 0 && (r.colors[len(r.colors)-1] != s.color || r.blinks[len(r.blinks)-1] != s.blink) {
+				t.Errorf("last render: (%v, %v), wants (%v, %v)", r.colors[len(r.colors)-1], r.blinks[len(r.blinks)-1], s.color, s.blink)
+			}
+		})
+	}
+
+	if sources := c.Sources(); !reflect.DeepEqual(sources, []string{"record", SourceState}) {
+		t.Errorf("sources: %v", sources)
+	}
+}
+
+func TestCompositor_TTL(t *testing.T) {
+	r := renderRecorder{}
+	clk := clock.NewFake(time.Now())
+	c := NewCompositor(r.render, clk)
+
+	c.Set(SourceState, Layer{Priority: PriorityState, Color: colorRef(led.ColorBlue)})
+	c.Set("notification", Layer{Priority: 200, Color: colorRef(led.ColorWhite), TTL: time.Second})
+	c.Set("fault", Layer{Priority: 100, Color: colorRef(led.ColorRed), TTL: 2 * time.Second})
+
+	clk.Advance(999 * time.Millisecond)
+	if out := c.Output(); out.Color != led.ColorWhite || out.Source != "notification" {
+		t.Errorf("output before expiry: %+v, wants %v from notification", out, led.ColorWhite)
+	}
+
+	clk.Advance(time.Millisecond)
+	if out := c.Output(); out.Color != led.ColorRed || out.Source != "fault" {
+		t.Errorf("output after notification expiry: %+v, wants %v from fault", out, led.ColorRed)
+	}
+
+	clk.Advance(time.Second)
+	if out := c.Output(); out.Color != led.ColorBlue || out.Source != SourceState {
+		t.Errorf("output after fault expiry: %+v, wants %v from state", out, led.ColorBlue)
+	}
+	if n := r.renders(); n != 4 {
+		t.Errorf("%v renders, wants 4", n)
+	}
+
+	// Layers don't expire once closed
+	c.Set("notification", Layer{Priority: 200, Color: colorRef(led.ColorWhite), TTL: time.Second})
+	c.Close()
+	clk.Advance(time.Second)
+	if out := c.Output(); out.Color != led.ColorWhite {
+		t.Errorf("output after close: %v, wants %v", out.Color, led.ColorWhite)
+	}
+}
+
+func TestLedPart_SetLayer(t *testing.T) {
+	l := fakeLed{}
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeBrake)
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	p.SetLayer("fault", Layer{Priority: 100, Color: colorRef(led.ColorRed), Blink: blinkRef(led.BlinkFrequency(4))})
+	if l.color != led.ColorRed || !l.blink {
+		t.Errorf("fault layer: (%v, blink=%v), wants (%v, true)", l.color, l.blink, led.ColorRed)
+	}
+
+	// Car state still tracked under fault layer
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	if l.color != led.ColorRed {
+		t.Errorf("color under fault layer: %v, wants %v", l.color, led.ColorRed)
+	}
+
+	p.ClearLayer("fault")
+	if l.color != led.ColorBlue || l.blink {
+		t.Errorf("color after clear: (%v, blink=%v), wants (%v, false)", l.color, l.blink, led.ColorBlue)
+	}
+}
+
+func TestLedPart_RenderBlinkChanges(t *testing.T) {
+	l := fakeLed{}
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeBrake)
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	p.onRecord(nil, testtools.NewFakeMessageFromProtobuf("record", &events.SwitchRecordMessage{Enabled: true}))
+	if l.blinkCalls != 2 || !l.blink {
+		t.Fatalf("blink calls after record: (%v, blink=%v), wants (2, true)", l.blinkCalls, l.blink)
+	}
+
+	// Color change with same blink doesn't restart blink
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	p.SetLayer("fault", Layer{Priority: 100, Color: colorRef(led.ColorRed)})
+	if l.color != led.ColorRed || l.blinkCalls != 2 {
+		t.Errorf("color change: (%v, blink calls=%v), wants (%v, 2)", l.color, l.blinkCalls, led.ColorRed)
+	}
+
+	p.onRecord(nil, testtools.NewFakeMessageFromProtobuf("record", &events.SwitchRecordMessage{Enabled: false}))
+	if l.blinkCalls != 3 || l.blink {
+		t.Errorf("blink calls after record end: (%v, blink=%v), wants (3, false)", l.blinkCalls, l.blink)
+	}
+}
+
+func TestLedPart_RecordLayer(t *testing.T) {
+	l := fakeLed{}
+	fast := led.BlinkFrequency(10)
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeRules, WithRules(Rules{
+		{Topics: map[string]string{"battery": "low"}, Color: "red", Blink: &fast},
+		{Color: "green"},
+	}))
+
+	p.onTopic(nil, testtools.NewFakeMessage("battery", []byte("low")))
+	p.onRecord(nil, testtools.NewFakeMessageFromProtobuf("record", &events.SwitchRecordMessage{Enabled: true}))
+	if out := p.layers().Output(); out.Color != led.ColorRed || out.Blink != recordBlinkPattern || out.BlinkSource != SourceRecord {
+		t.Errorf("record layer: %+v, wants (%v, %v) from %v", out, led.ColorRed, recordBlinkPattern, SourceRecord)
+	}
+
+	p.onRecord(nil, testtools.NewFakeMessageFromProtobuf("record", &events.SwitchRecordMessage{Enabled: false}))
+	if out := p.layers().Output(); out.Blink != fast || out.BlinkSource != SourceState {
+		t.Errorf("after record: %+v, wants blink %v from %v", out, fast, SourceState)
+	}
+	if sources := p.layers().Sources(); !reflect.DeepEqual(sources, []string{SourceState}) {
+		t.Errorf("sources after record: %v, wants %v", sources, []string{SourceState})
+	}
+}
@@ -0,0 +1,117 @@
+package part
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"time"
+)
+
+// ConfigUpdate is a json document that changes led configuration at runtime, missing fields keep
+// current value. Durations are written as text (ex: "500ms").
+type ConfigUpdate struct {
+	Mode *LedMode `json:"mode,omitempty"`
+	// Theme resets palette to a built-in theme before Colors are applied
+	Theme  *string              `json:"theme,omitempty"`
+	Colors map[string]led.Color `json:"colors,omitempty"`
+	// BrakeLevels uses ParseBrakeLevels format, empty string restores levels built from palette
+	BrakeLevels        *string  `json:"brake_levels,omitempty"`
+	BrakeHysteresis    *float32 `json:"brake_hysteresis,omitempty"`
+	BrakeMinHold       *string  `json:"brake_min_hold,omitempty"`
+	BrakeHold          *string  `json:"brake_hold,omitempty"`
+	DecelerationDrop   *float32 `json:"deceleration_drop,omitempty"`
+	DecelerationWindow *string  `json:"deceleration_window,omitempty"`
+	ThrottleGradient   *string  `json:"throttle_gradient,omitempty"`
+	// Rules enables LedModeRules, unless Mode is set
+	Rules Rules `json:"rules,omitempty"`
+}
+
+// ParseConfigUpdate reads a json ConfigUpdate, unknown fields are rejected
+func ParseConfigUpdate(content []byte) (ConfigUpdate, error) {
+	var u ConfigUpdate
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&u); err != nil {
+		return ConfigUpdate{}, fmt.Errorf("unable to parse config: %v", err)
+	}
+	return u, nil
+}
+
+// Apply returns mode and config changed by update, mode and cfg aren't modified
+func (u ConfigUpdate) Apply(mode LedMode, cfg StrategyConfig) (LedMode, StrategyConfig, error) {
+	palette := *cfg.palette()
+	if u.Theme != nil {
+		t, err := Theme(*u.Theme)
+		if err != nil {
+			return mode, cfg, err
+		}
+		palette = t
+	}
+	colors := palette.colors()
+	for name, value := range u.Colors {
+		c, ok := colors[name]
+		if !ok {
+			return mode, cfg, fmt.Errorf("unknown palette color '%v'", name)
+		}
+		*c = value
+	}
+	cfg.Palette = &palette
+
+	if u.BrakeLevels != nil {
+		cfg.BrakeLevels = nil
+		if *u.BrakeLevels != "" {
+			levels, err := ParseBrakeLevels(*u.BrakeLevels)
+			if err != nil {
+				return mode, cfg, err
+			}
+			cfg.BrakeLevels = levels
+		}
+	}
+	if u.BrakeHysteresis != nil {
+		cfg.BrakeHysteresis = *u.BrakeHysteresis
+	}
+	if u.DecelerationDrop != nil {
+		cfg.DecelerationDrop = *u.DecelerationDrop
+	}
+	for _, d := range []struct {
+		name  string
+		value *string
+		dest  *time.Duration
+	}{
+		{"brake_min_hold", u.BrakeMinHold, &cfg.BrakeMinHold},
+		{"brake_hold", u.BrakeHold, &cfg.BrakeHold},
+		{"deceleration_window", u.DecelerationWindow, &cfg.DecelerationWindow},
+	} {
+		if d.value == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.value)
+		if err != nil {
+			return mode, cfg, fmt.Errorf("invalid %v: %v", d.name, err)
+		}
+		*d.dest = v
+	}
+	if u.ThrottleGradient != nil {
+		g, err := led.ParseGradient(*u.ThrottleGradient)
+		if err != nil {
+			return mode, cfg, err
+		}
+		cfg.ThrottleGradient = g
+	}
+
+	if u.Rules != nil {
+		if err := u.Rules.Validate(); err != nil {
+			return mode, cfg, err
+		}
+		cfg.Rules = u.Rules
+		mode = LedModeRules
+	}
+	if u.Mode != nil {
+		mode = *u.Mode
+	}
+	if err := cfg.Validate(); err != nil {
+		return mode, cfg, err
+	}
+	return mode, cfg, nil
+}
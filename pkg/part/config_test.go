@@ -0,0 +1,124 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigUpdate_Apply(t *testing.T) {
+	cfg := StrategyConfig{BrakeHysteresis: DefaultBrakeHysteresis, BrakeMinHold: DefaultBrakeMinHold}
+
+	u, err := ParseConfigUpdate([]byte(`{
+  "theme": "warm",
+  "colors": {"pilot": "white"},
+  "brake_levels": "-0.1=red",
+  "brake_hysteresis": 0.1,
+  "brake_hold": "1s",
+  "throttle_gradient": "0=black,1=white"
+}`))
+	if err != nil {
+		t.Fatalf("unable to parse config: %v", err)
+	}
+	mode, updated, err := u.Apply(LedModeBrake, cfg)
+	if err != nil {
+		t.Fatalf("unable to apply config: %v", err)
+	}
+
+	warm, _ := Theme("warm")
+	if mode != LedModeBrake {
+		t.Errorf("mode: %v, wants %v", mode, LedModeBrake)
+	}
+	if updated.Palette.Pilot != led.ColorWhite || updated.Palette.User != warm.User {
+		t.Errorf("palette: %+v, wants warm theme with white pilot", updated.Palette)
+	}
+	if !reflect.DeepEqual(updated.BrakeLevels, []BrakeLevel{{Threshold: -0.1, Color: led.ColorRed}}) {
+		t.Errorf("brake levels: %v", updated.BrakeLevels)
+	}
+	if updated.BrakeHysteresis != 0.1 || updated.BrakeHold != time.Second || updated.BrakeMinHold != DefaultBrakeMinHold {
+		t.Errorf("brake settings: %+v", updated)
+	}
+	if updated.ThrottleGradient.String() != "0=#000000,1=#FFFFFF" {
+		t.Errorf("throttle gradient: %v", updated.ThrottleGradient)
+	}
+	if cfg.Palette != nil || cfg.BrakeHold != 0 {
+		t.Errorf("source config modified: %+v", cfg)
+	}
+
+	u, _ = ParseConfigUpdate([]byte(`{"rules": [{"color": "red"}]}`))
+	if mode, updated, _ := u.Apply(LedModeBrake, cfg); mode != LedModeRules || len(updated.Rules) != 1 {
+		t.Errorf("rules config: (%v, %v), wants rules mode", mode, updated.Rules)
+	}
+
+	for _, invalid := range []string{
+		`{"theme": "dark"}`,
+		`{"colors": {"driver": "red"}}`,
+		`{"brake_levels": "-0.1=red,-0.05=white"}`,
+		`{"brake_hold": "long"}`,
+		`{"brake_hold": "-5s"}`,
+		`{"brake_hysteresis": -3}`,
+		`{"deceleration_drop": -1}`,
+		`{"deceleration_window": "-200ms"}`,
+		`{"rules": [{"drive_modes": ["auto"], "color": "red"}]}`,
+	} {
+		u, err := ParseConfigUpdate([]byte(invalid))
+		if err != nil {
+			t.Errorf("unable to parse %v: %v", invalid, err)
+			continue
+		}
+		if _, _, err := u.Apply(LedModeBrake, cfg); err == nil {
+			t.Errorf("no error applying %v", invalid)
+		}
+	}
+
+	if _, err := ParseConfigUpdate([]byte(`{"colour": {}}`)); err == nil {
+		t.Errorf("no error for unknown field")
+	}
+}
+
+func TestLedPart_OnConfig(t *testing.T) {
+	l := fakeLed{}
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeBrake)
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	p.onSpeedZone(nil, testtools.NewFakeMessageFromProtobuf("speedzone", &events.SpeedZoneMessage{SpeedZone: events.SpeedZone_SLOW}))
+	if l.color != led.ColorBlue {
+		t.Fatalf("color: %v, wants %v", l.color, led.ColorBlue)
+	}
+
+	cases := []struct {
+		name    string
+		payload string
+		mode    LedMode
+		color   led.Color
+	}{
+		{"led rendered from current state", `{"mode": "speedzone"}`, LedModeSpeedZone, led.ColorRed},
+		{"palette", `{"colors": {"speed_zone_slow": "aqua"}}`, LedModeSpeedZone, led.ColorAqua},
+		{"invalid json keeps config", `{"mode": `, LedModeSpeedZone, led.ColorAqua},
+		{"unknown mode keeps config", `{"mode": "disco"}`, LedModeSpeedZone, led.ColorAqua},
+		{"invalid rules keep config", `{"rules": [{"speed_zones": ["warp"], "color": "white"}]}`, LedModeSpeedZone, led.ColorAqua},
+		{"empty payload is ignored", ``, LedModeSpeedZone, led.ColorAqua},
+		{"rules", `{"rules": [{"speed_zones": ["slow"], "color": "white"}]}`, LedModeRules, led.ColorWhite},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p.onConfig(nil, testtools.NewFakeMessage("config", []byte(c.payload)))
+			if mode, _ := p.Config(); mode != c.mode {
+				t.Errorf("mode: %v, wants %v", mode, c.mode)
+			}
+			if l.color != c.color {
+				t.Errorf("color: %v, wants %v", l.color, c.color)
+			}
+		})
+	}
+}
+
+func TestDiffTopics(t *testing.T) {
+	added, removed := diffTopics([]string{"a", "b"}, []string{"b", "c"})
+	if !reflect.DeepEqual(added, []string{"c"}) || !reflect.DeepEqual(removed, []string{"a"}) {
+		t.Errorf("diffTopics: (%v, %v), wants ([c], [a])", added, removed)
+	}
+}
@@ -0,0 +1,71 @@
+package part
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"time"
+)
+
+// SourceOverride is the source of layer set by override commands
+const SourceOverride = "override"
+
+// OverrideCommand is a json document that forces led output, ex:
+//
+//	{"color": "white", "blink": "10hz", "ttl": "30s", "reason": "identify car"}
+//
+// Override hides layers with a lower priority until TTL elapses or a clear command ({"clear": true}) is
+// received.
+type OverrideCommand struct {
+	Color *led.Color        `json:"color,omitempty"`
+	Blink *led.BlinkPattern `json:"blink,omitempty"`
+	// Priority is PriorityOverride if not set, it must be greater than PriorityState
+	Priority *int `json:"priority,omitempty"`
+	// TTL is a duration written as text (ex: "30s"), override is kept until cleared if not set
+	TTL    string `json:"ttl,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Clear  bool   `json:"clear,omitempty"`
+}
+
+// ParseOverrideCommand reads a json OverrideCommand, an empty payload is a clear command
+func ParseOverrideCommand(content []byte) (OverrideCommand, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return OverrideCommand{Clear: true}, nil
+	}
+	var c OverrideCommand
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return OverrideCommand{}, fmt.Errorf("unable to parse override command: %v", err)
+	}
+	return c, nil
+}
+
+// Layer returns layer to display for command
+func (c OverrideCommand) Layer() (Layer, error) {
+	if c.Color == nil && c.Blink == nil {
+		return Layer{}, fmt.Errorf("override command without color nor blink")
+	}
+	l := Layer{Priority: PriorityOverride, Color: c.Color, Blink: c.Blink, Reason: c.Reason}
+	if c.Priority != nil {
+		if *c.Priority <= PriorityState {
+			return Layer{}, fmt.Errorf("invalid override priority %v, must be greater than %v", *c.Priority, PriorityState)
+		}
+		l.Priority = *c.Priority
+	}
+	if c.TTL != "" {
+		ttl, err := time.ParseDuration(c.TTL)
+		if err != nil {
+			return Layer{}, fmt.Errorf("invalid override ttl: %v", err)
+		}
+		if ttl <= 0 {
+			return Layer{}, fmt.Errorf("invalid override ttl %v, must be positive", ttl)
+		}
+		l.TTL = ttl
+	}
+	if l.Reason == "" {
+		l.Reason = "remote override"
+	}
+	return l, nil
+}
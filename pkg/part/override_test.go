@@ -0,0 +1,97 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"testing"
+	"time"
+)
+
+func TestOverrideCommand_Layer(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload string
+		clear   bool
+		layer   Layer
+	}{
+		{"empty payload clears", ``, true, Layer{}},
+		{"clear", `{"clear": true}`, true, Layer{}},
+		{"defaults", `{"color": "white"}`, false, Layer{Priority: PriorityOverride, Color: colorRef(led.ColorWhite), Reason: "remote override"}},
+		{"full", `{"color": "#FF0000", "blink": "10hz", "priority": 150, "ttl": "30s", "reason": "identify car"}`, false,
+			Layer{Priority: 150, Color: colorRef(led.ColorRed), Blink: blinkRef(led.BlinkFrequency(10)), TTL: 30 * time.Second, Reason: "identify car"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			command, err := ParseOverrideCommand([]byte(c.payload))
+			if err != nil {
+				t.Fatalf("unable to parse command: %v", err)
+			}
+			if command.Clear != c.clear {
+				t.Errorf("clear: %v, wants %v", command.Clear, c.clear)
+			}
+			if c.clear {
+				return
+			}
+			l, err := command.Layer()
+			if err != nil {
+				t.Fatalf("unable to build layer: %v", err)
+			}
+			if l.Priority != c.layer.Priority || l.TTL != c.layer.TTL || l.Reason != c.layer.Reason ||
+				*l.Color != *c.layer.Color || (l.Blink == nil) != (c.layer.Blink == nil) || l.Blink != nil && *l.Blink != *c.layer.Blink {
+				t.Errorf("layer: %+v, wants %+v", l, c.layer)
+			}
+		})
+	}
+
+	if _, err := ParseOverrideCommand([]byte(`{"colour": "white"}`)); err == nil {
+		t.Errorf("no error for unknown field")
+	}
+	for _, invalid := range []string{`{}`, `{"color": "white", "ttl": "soon"}`, `{"color": "white", "ttl": "-1s"}`,
+		`{"color": "white", "priority": 0}`, `{"color": "white", "priority": -10}`} {
+		command, err := ParseOverrideCommand([]byte(invalid))
+		if err != nil {
+			t.Errorf("unable to parse %v: %v", invalid, err)
+			continue
+		}
+		if _, err := command.Layer(); err == nil {
+			t.Errorf("no error for %v", invalid)
+		}
+	}
+}
+
+func TestLedPart_OnOverride(t *testing.T) {
+	l := fakeLed{}
+	clk := clock.NewFake(time.Now())
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeBrake, WithClock(clk))
+	defer p.layers().Close()
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	p.onOverride(nil, testtools.NewFakeMessage("override", []byte(`{"color": "white", "blink": "10hz", "ttl": "30s"}`)))
+	if l.color != led.ColorWhite || !l.blink {
+		t.Errorf("override: (%v, blink=%v), wants (%v, true)", l.color, l.blink, led.ColorWhite)
+	}
+
+	// Invalid command keeps current override
+	p.onOverride(nil, testtools.NewFakeMessage("override", []byte(`{"color": "white", "ttl": "soon"}`)))
+	if l.color != led.ColorWhite || !l.blink {
+		t.Errorf("override after invalid command: (%v, blink=%v), wants (%v, true)", l.color, l.blink, led.ColorWhite)
+	}
+
+	// Rendering resumes from state changed during override
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	clk.Advance(30 * time.Second)
+	if out := p.layers().Output(); out.Color != led.ColorBlue || out.Blink.Enabled() || out.Source != SourceState {
+		t.Errorf("output after ttl: %+v, wants steady state output", out)
+	}
+
+	p.onOverride(nil, testtools.NewFakeMessage("override", []byte(`{"color": "red"}`)))
+	if l.color != led.ColorRed {
+		t.Errorf("override without ttl: %v, wants %v", l.color, led.ColorRed)
+	}
+	p.onOverride(nil, testtools.NewFakeMessage("override", []byte(`{"clear": true}`)))
+	if l.color != led.ColorBlue {
+		t.Errorf("color after clear: %v, wants %v", l.color, led.ColorBlue)
+	}
+}
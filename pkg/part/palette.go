@@ -0,0 +1,153 @@
+package part
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Palette defines colors displayed for each led state
+type Palette struct {
+	User    led.Color `json:"user"`
+	Copilot led.Color `json:"copilot"`
+	Pilot   led.Color `json:"pilot"`
+
+	SpeedZoneUnknown led.Color `json:"speed_zone_unknown"`
+	SpeedZoneSlow    led.Color `json:"speed_zone_slow"`
+	SpeedZoneNormal  led.Color `json:"speed_zone_normal"`
+	SpeedZoneFast    led.Color `json:"speed_zone_fast"`
+
+	// Brake ladder, from lightest to hardest brake
+	BrakeLight  led.Color `json:"brake_light"`
+	BrakeMedium led.Color `json:"brake_medium"`
+	BrakeHigh   led.Color `json:"brake_high"`
+	BrakeMax    led.Color `json:"brake_max"`
+}
+
+const DefaultTheme = "default"
+
+// DefaultPalette is the historical led behaviour
+var DefaultPalette = Palette{
+	User:             led.ColorGreen,
+	Copilot:          led.ColorAqua,
+	Pilot:            led.ColorBlue,
+	SpeedZoneUnknown: led.ColorWhite,
+	SpeedZoneSlow:    led.ColorRed,
+	SpeedZoneNormal:  led.ColorYellow,
+	SpeedZoneFast:    led.ColorBlue,
+	BrakeLight:       led.ColorWhite,
+	BrakeMedium:      led.ColorYellow,
+	BrakeHigh:        led.ColorRed,
+	BrakeMax:         led.ColorPurple,
+}
+
+var themes = map[string]Palette{
+	DefaultTheme: DefaultPalette,
+	// colorblind uses Okabe-Ito colors, distinguishable with common color vision deficiencies
+	"colorblind": {
+		User:             led.Color{Red: 0x00, Green: 0x9E, Blue: 0x73},
+		Copilot:          led.Color{Red: 0x56, Green: 0xB4, Blue: 0xE9},
+		Pilot:            led.Color{Red: 0x00, Green: 0x72, Blue: 0xB2},
+		SpeedZoneUnknown: led.ColorWhite,
+		SpeedZoneSlow:    led.Color{Red: 0xD5, Green: 0x5E, Blue: 0x00},
+		SpeedZoneNormal:  led.Color{Red: 0xF0, Green: 0xE4, Blue: 0x42},
+		SpeedZoneFast:    led.Color{Red: 0x00, Green: 0x72, Blue: 0xB2},
+		BrakeLight:       led.ColorWhite,
+		BrakeMedium:      led.Color{Red: 0xE6, Green: 0x9F, Blue: 0x00},
+		BrakeHigh:        led.Color{Red: 0xD5, Green: 0x5E, Blue: 0x00},
+		BrakeMax:         led.Color{Red: 0xCC, Green: 0x79, Blue: 0xA7},
+	},
+	// warm avoids blue and green tones to tell apart cars racing together
+	"warm": {
+		User:             led.Color{Red: 0xFF, Green: 0xA5, Blue: 0x00},
+		Copilot:          led.Color{Red: 0xFF, Green: 0x69, Blue: 0xB4},
+		Pilot:            led.Color{Red: 0xFF, Green: 0x45, Blue: 0x00},
+		SpeedZoneUnknown: led.ColorWhite,
+		SpeedZoneSlow:    led.Color{Red: 0x8B, Green: 0x00, Blue: 0x00},
+		SpeedZoneNormal:  led.Color{Red: 0xFF, Green: 0xD7, Blue: 0x00},
+		SpeedZoneFast:    led.Color{Red: 0xFF, Green: 0x45, Blue: 0x00},
+		BrakeLight:       led.ColorWhite,
+		BrakeMedium:      led.ColorYellow,
+		BrakeHigh:        led.ColorRed,
+		BrakeMax:         led.ColorPurple,
+	},
+}
+
+// Theme returns built-in palette registered with name
+func Theme(name string) (Palette, error) {
+	p, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return Palette{}, fmt.Errorf("unknown theme '%v', available themes: %v", name, strings.Join(ThemeNames(), ", "))
+	}
+	return p, nil
+}
+
+// ThemeNames lists built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// colors indexes palette colors by their json name
+func (p *Palette) colors() map[string]*led.Color {
+	return map[string]*led.Color{
+		"user":               &p.User,
+		"copilot":            &p.Copilot,
+		"pilot":              &p.Pilot,
+		"speed_zone_unknown": &p.SpeedZoneUnknown,
+		"speed_zone_slow":    &p.SpeedZoneSlow,
+		"speed_zone_normal":  &p.SpeedZoneNormal,
+		"speed_zone_fast":    &p.SpeedZoneFast,
+		"brake_light":        &p.BrakeLight,
+		"brake_medium":       &p.BrakeMedium,
+		"brake_high":         &p.BrakeHigh,
+		"brake_max":          &p.BrakeMax,
+	}
+}
+
+// WithOverrides returns a copy of palette with colors replaced by overrides, written as a comma
+// separated list of name=color (ex: "user=#FF8800,brake_max=rgb(255, 0, 128)")
+func (p Palette) WithOverrides(overrides string) (Palette, error) {
+	if strings.TrimSpace(overrides) == "" {
+		return p, nil
+	}
+	colors := p.colors()
+	for _, o := range led.SplitList(overrides) {
+		name, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return Palette{}, fmt.Errorf("invalid palette override '%v', must be name=color", o)
+		}
+		c, ok := colors[strings.TrimSpace(name)]
+		if !ok {
+			return Palette{}, fmt.Errorf("unknown palette color '%v'", name)
+		}
+		if err := c.UnmarshalText([]byte(value)); err != nil {
+			return Palette{}, fmt.Errorf("invalid value for palette color '%v': %v", name, err)
+		}
+	}
+	return p, nil
+}
+
+// LoadPalette reads a json file of colors ({"user": "#00FF00", "brake_max": "purple", ...}), colors
+// missing from file are taken from base
+func LoadPalette(path string, base Palette) (Palette, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Palette{}, fmt.Errorf("unable to read palette file: %v", err)
+	}
+	p := base
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return Palette{}, fmt.Errorf("unable to parse palette file '%v': %v", path, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,98 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTheme(t *testing.T) {
+	p, err := Theme("Default")
+	if err != nil {
+		t.Fatalf("unable to load default theme: %v", err)
+	}
+	if p != DefaultPalette {
+		t.Errorf("default theme: %v, wants %v", p, DefaultPalette)
+	}
+
+	for _, name := range ThemeNames() {
+		p, err := Theme(name)
+		if err != nil {
+			t.Errorf("unable to load theme %v: %v", name, err)
+		}
+		if p.User == p.Copilot || p.Copilot == p.Pilot || p.User == p.Pilot {
+			t.Errorf("theme %v doesn't distinguish drive modes: %v", name, p)
+		}
+	}
+
+	if _, err := Theme("unknown"); err == nil {
+		t.Errorf("unknown theme must fail")
+	}
+}
+
+func TestPalette_WithOverrides(t *testing.T) {
+	p, err := DefaultPalette.WithOverrides("user=#FF8800, brake_max=rgb(255, 0, 128),pilot=white")
+	if err != nil {
+		t.Fatalf("unable to override palette: %v", err)
+	}
+	expected := DefaultPalette
+	expected.User = led.Color{Red: 255, Green: 136}
+	expected.BrakeMax = led.Color{Red: 255, Blue: 128}
+	expected.Pilot = led.ColorWhite
+	if p != expected {
+		t.Errorf("WithOverrides(): %v, wants %v", p, expected)
+	}
+	if DefaultPalette.User != led.ColorGreen {
+		t.Errorf("WithOverrides() must not change original palette")
+	}
+
+	for _, o := range []string{"user", "unknown=red", "user=pink"} {
+		if _, err := DefaultPalette.WithOverrides(o); err == nil {
+			t.Errorf("WithOverrides(%q) must fail", o)
+		}
+	}
+}
+
+func TestLoadPalette(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "palette.json")
+	if err := os.WriteFile(path, []byte(`{"copilot": "#123456", "speed_zone_fast": "rgb(1, 2, 3)"}`), 0644); err != nil {
+		t.Fatalf("unable to write palette: %v", err)
+	}
+
+	p, err := LoadPalette(path, DefaultPalette)
+	if err != nil {
+		t.Fatalf("unable to load palette: %v", err)
+	}
+	expected := DefaultPalette
+	expected.Copilot = led.Color{Red: 0x12, Green: 0x34, Blue: 0x56}
+	expected.SpeedZoneFast = led.Color{Red: 1, Green: 2, Blue: 3}
+	if p != expected {
+		t.Errorf("LoadPalette(): %v, wants %v", p, expected)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"driver": "red"}`), 0644); err != nil {
+		t.Fatalf("unable to write palette: %v", err)
+	}
+	if _, err := LoadPalette(path, DefaultPalette); err == nil {
+		t.Errorf("unknown color name must fail")
+	}
+}
+
+func TestLedPart_WithPalette(t *testing.T) {
+	palette, _ := Theme("colorblind")
+	l := led.NewSimLed()
+	p := NewPart(nil, l, "drive", "record", "speedzone", "throttle", LedModeBrake, WithPalette(palette))
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_COPILOT}))
+	if l.Color() != palette.Copilot {
+		t.Errorf("copilot color: %v, wants %v", l.Color(), palette.Copilot)
+	}
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: -0.6}))
+	if l.Color() != palette.BrakeHigh {
+		t.Errorf("brake color: %v, wants %v", l.Color(), palette.BrakeHigh)
+	}
+}
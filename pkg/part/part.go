@@ -1,28 +1,196 @@
 package part
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/cyrilix/robocar-base/service"
+	"github.com/cyrilix/robocar-led/pkg/clock"
 	"github.com/cyrilix/robocar-led/pkg/led"
 	"github.com/cyrilix/robocar-protobuf/go/events"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// LedMode is the name of a registered Strategy
+type LedMode string
+
 const (
-	LedModeBrake LedMode = iota
-	LedModeSpeedZone
+	LedModeBrake     LedMode = "brake"
+	LedModeSpeedZone LedMode = "speedzone"
+	// LedModeThrottleGradient displays throttle as a continuous color gradient
+	LedModeThrottleGradient LedMode = "throttle-gradient"
+	// LedModeRules renders rules given with WithRules
+	LedModeRules LedMode = "rules"
 )
 
-type LedMode int
+// DefaultThrottleGradient is blue at full reverse, off at zero, then green to red as forward throttle rises
+var DefaultThrottleGradient = led.Gradient{
+	{Position: -1, Color: led.ColorBlue},
+	{Position: 0, Color: led.ColorBlack},
+	{Position: 0.05, Color: led.ColorGreen},
+	{Position: 1, Color: led.ColorRed},
+}
+
+// recordBlinkPattern signals video recording
+var recordBlinkPattern = led.BlinkFrequency(2)
+
+type Option func(p *LedPart)
+
+// WithFadeDuration makes led fade to each new color during d instead of switching instantly
+func WithFadeDuration(d time.Duration) Option {
+	return func(p *LedPart) {
+		p.fadeDuration = d
+	}
+}
+
+// WithClock overrides clock used for timing logic, default is system clock
+func WithClock(c clock.Clock) Option {
+	return func(p *LedPart) {
+		p.clock = c
+		p.config.Clock = c
+	}
+}
+
+// WithBrightness configures initial global brightness (0-1) of led
+func WithBrightness(b float64) Option {
+	return func(p *LedPart) {
+		p.brightness = b
+	}
+}
+
+// WithBrightnessTopic subscribes to topic to adjust global brightness at runtime, payload is a
+// text value in range [0, 1]
+func WithBrightnessTopic(topic string) Option {
+	return func(p *LedPart) {
+		p.onBrightnessTopic = topic
+	}
+}
+
+// WithPalette overrides colors displayed for each led state
+func WithPalette(palette Palette) Option {
+	return func(p *LedPart) {
+		p.config.Palette = &palette
+	}
+}
+
+// WithBrakeLevels overrides brake ladder built from palette brake colors
+func WithBrakeLevels(levels []BrakeLevel) Option {
+	return func(p *LedPart) {
+		p.config.BrakeLevels = levels
+	}
+}
+
+// WithBrakeHysteresis configures throttle margin needed to release a brake level and duration after
+// which a brake level is released without margin
+func WithBrakeHysteresis(hysteresis float32, minHold time.Duration) Option {
+	return func(p *LedPart) {
+		p.config.BrakeHysteresis = hysteresis
+		p.config.BrakeMinHold = minHold
+	}
+}
+
+// WithBrakeHold keeps brake color displayed during d after braking ends
+func WithBrakeHold(d time.Duration) Option {
+	return func(p *LedPart) {
+		p.config.BrakeHold = d
+	}
+}
+
+// WithDecelerationBrake detects braking when throttle drops by at least drop within window, even
+// if throttle stays positive
+func WithDecelerationBrake(drop float32, window time.Duration) Option {
+	return func(p *LedPart) {
+		p.config.DecelerationDrop = drop
+		p.config.DecelerationWindow = window
+	}
+}
+
+// WithThrottleGradient overrides gradient used by LedModeThrottleGradient
+func WithThrottleGradient(g led.Gradient) Option {
+	return func(p *LedPart) {
+		p.config.ThrottleGradient = g
+	}
+}
+
+// WithConfigTopic subscribes to topic to reload configuration at runtime, payload is a json
+// ConfigUpdate
+func WithConfigTopic(topic string) Option {
+	return func(p *LedPart) {
+		p.onConfigTopic = topic
+	}
+}
+
+// WithStrategyConfig replaces whole strategy config
+func WithStrategyConfig(cfg StrategyConfig) Option {
+	return func(p *LedPart) {
+		p.config = cfg
+	}
+}
+
+// WithRules configures rules rendered by LedModeRules
+func WithRules(rules Rules) Option {
+	return func(p *LedPart) {
+		p.config.Rules = rules
+	}
+}
+
+// WithOverrideTopic subscribes to topic to force led output remotely, payload is a json
+// OverrideCommand
+func WithOverrideTopic(topic string) Option {
+	return func(p *LedPart) {
+		p.onOverrideTopic = topic
+	}
+}
+
+// WithStateTopic publishes led state each time it changes, payload is a protobuf
+// ledevents.LedStateMessage
+func WithStateTopic(topic string) Option {
+	return func(p *LedPart) {
+		p.stateTopic = topic
+	}
+}
+
+// WithStateJSONTopic publishes led state each time it changes, payload is a json LedState
+func WithStateJSONTopic(topic string) Option {
+	return func(p *LedPart) {
+		p.stateJSONTopic = topic
+	}
+}
+
+// WithPublishOptions configures qos and retain flag of published messages
+func WithPublishOptions(qos byte, retain bool) Option {
+	return func(p *LedPart) {
+		p.qos = qos
+		p.retain = retain
+	}
+}
+
+// WithStrategy uses s instead of strategy registered for led mode
+func WithStrategy(s Strategy) Option {
+	return func(p *LedPart) {
+		p.strategy = s
+	}
+}
 
-func NewPart(client mqtt.Client, driveModeTopic, recordTopic, speedZoneTopic, throttleTopic string, ledMode LedMode) *LedPart {
-	return &LedPart{
-		led:              led.New(),
+func NewPart(client mqtt.Client, l led.ColoredLed, driveModeTopic, recordTopic, speedZoneTopic, throttleTopic string, ledMode LedMode, opts ...Option) *LedPart {
+	p := LedPart{
+		led:        l,
+		clock:      clock.New(),
+		brightness: 1,
+		config: StrategyConfig{
+			BrakeHysteresis:    DefaultBrakeHysteresis,
+			BrakeMinHold:       DefaultBrakeMinHold,
+			DecelerationWindow: DefaultDecelerationWindow,
+		},
+		stopped:          make(chan struct{}),
 		mode:             ledMode,
+		stateMode:        ledMode,
 		client:           client,
 		onDriveModeTopic: driveModeTopic,
 		onRecordTopic:    recordTopic,
@@ -37,17 +205,62 @@ func NewPart(client mqtt.Client, driveModeTopic, recordTopic, speedZoneTopic, th
 		muThrottle:       sync.Mutex{},
 	}
 
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if p.strategy == nil {
+		p.config.Clock = p.clock
+		p.strategy = p.newStrategy()
+	}
+
+	p.dimmer = led.NewDimmer(l)
+	if err := p.dimmer.SetBrightness(p.brightness); err != nil {
+		zap.S().Errorf("unable to set led brightness, use full brightness: %v", err)
+	}
+	p.led = p.dimmer
+
+	p.animator = led.NewAnimator(p.led, p.clock)
+	p.led = p.animator
+	return &p
 }
 
 type LedPart struct {
-	led              led.ColoredLed
+	led          led.ColoredLed
+	animator     *led.Animator
+	fadeDuration time.Duration
+	dimmer       *led.Dimmer
+	brightness   float64
+	clock        clock.Clock
+	// muStrategy protects strategy and its configuration, replaced on reload
+	muStrategy       sync.Mutex
 	mode             LedMode
+	config           StrategyConfig
+	strategy         Strategy
 	client           mqtt.Client
 	onDriveModeTopic string
 	onRecordTopic    string
 	onSpeedZoneTopic string
 	onThrottleTopic  string
 
+	onBrightnessTopic string
+	onConfigTopic     string
+	onOverrideTopic   string
+
+	stateTopic     string
+	stateJSONTopic string
+	qos            byte
+	retain         bool
+	// muState protects last published state and mode it reports
+	muState   sync.Mutex
+	stateMode LedMode
+	published *LedState
+
+	// subscribed lists topics of strategy subscribed once callbacks are registered
+	muSubscriptions sync.Mutex
+	registered      bool
+	subscribed      []string
+
 	muDriveMode   sync.Mutex
 	driveMode     events.DriveMode
 	muRecord      sync.Mutex
@@ -58,6 +271,19 @@ type LedPart struct {
 
 	muThrottle sync.Mutex
 	throttle   float32
+
+	// topicValues holds last payload of topics needed by strategy
+	muTopics    sync.Mutex
+	topicValues map[string]string
+
+	// compositor merges strategy output with layers of other sources
+	compositor     *Compositor
+	compositorOnce sync.Once
+	// rendered is last output forwarded to led, renders are serialized by compositor
+	rendered *Composite
+	// holdTimer refreshes color when asked by strategy output
+	holdTimer clock.Timer
+	stopped   chan struct{}
 }
 
 func (p *LedPart) Start() error {
@@ -72,7 +298,120 @@ func (p *LedPart) Start() error {
 func (p *LedPart) Stop() {
 	defer p.led.SetBlink(0)
 	defer p.led.SetColor(led.ColorBlack)
-	service.StopService("led", p.client, p.onDriveModeTopic, p.onRecordTopic, p.onSpeedZoneTopic, p.onThrottleTopic)
+	topics := []string{p.onDriveModeTopic, p.onRecordTopic, p.onSpeedZoneTopic, p.onThrottleTopic}
+	if p.onBrightnessTopic != "" {
+		topics = append(topics, p.onBrightnessTopic)
+	}
+	if p.onConfigTopic != "" {
+		topics = append(topics, p.onConfigTopic)
+	}
+	if p.onOverrideTopic != "" {
+		topics = append(topics, p.onOverrideTopic)
+	}
+	p.muSubscriptions.Lock()
+	topics = append(topics, p.subscribed...)
+	p.muSubscriptions.Unlock()
+	service.StopService("led", p.client, topics...)
+	if p.stopped != nil {
+		close(p.stopped)
+	}
+	if p.compositor != nil {
+		p.compositor.Close()
+	}
+}
+
+// SetLayer displays layer of source over layers with a lower priority
+func (p *LedPart) SetLayer(source string, l Layer) {
+	p.layers().Set(source, l)
+}
+
+// ClearLayer removes layer of source
+func (p *LedPart) ClearLayer(source string) {
+	p.layers().Clear(source)
+}
+
+// layers returns compositor, created on first use
+func (p *LedPart) layers() *Compositor {
+	p.compositorOnce.Do(func() {
+		clk := p.clock
+		if clk == nil {
+			clk = clock.New()
+		}
+		p.compositor = NewCompositor(p.render, clk)
+	})
+	return p.compositor
+}
+
+// render applies output of compositor to led
+func (p *LedPart) render(c Composite) {
+	last := p.rendered
+	p.rendered = &c
+	if last == nil || last.Color != c.Color || last.Animation != c.Animation {
+		p.setColor(c.Color, c.Animation, last != nil && last.Animation.Enabled())
+	}
+	// Setting blink restarts it, only changes are forwarded to keep phase on color changes
+	if last == nil || last.Blink != c.Blink {
+		p.led.SetBlinkPattern(c.Blink)
+	}
+	p.publishState(c)
+}
+
+// publishState publishes led state of rendered output
+func (p *LedPart) publishState(c Composite) {
+	p.muState.Lock()
+	defer p.muState.Unlock()
+	p.sendState(LedState{Color: c.Color, Blink: c.Blink, Mode: p.stateMode, Source: c.Source, Reason: c.Reason})
+}
+
+// republishState publishes last state again if mode changed without led output change
+func (p *LedPart) republishState() {
+	p.muState.Lock()
+	defer p.muState.Unlock()
+	if p.published == nil {
+		return
+	}
+	state := *p.published
+	state.Mode = p.stateMode
+	p.sendState(state)
+}
+
+// sendState publishes state if it changed since last publication, must be called with muState locked
+func (p *LedPart) sendState(state LedState) {
+	if p.client == nil || p.stateTopic == "" && p.stateJSONTopic == "" {
+		return
+	}
+	if p.published != nil && *p.published == state {
+		return
+	}
+	p.published = &state
+
+	if p.stateTopic != "" {
+		payload, err := proto.Marshal(state.Proto())
+		if err != nil {
+			zap.S().Errorf("unable to marshal led state: %v", err)
+		} else {
+			p.publish(p.stateTopic, payload)
+		}
+	}
+	if p.stateJSONTopic != "" {
+		payload, err := json.Marshal(state)
+		if err != nil {
+			zap.S().Errorf("unable to marshal led state: %v", err)
+			return
+		}
+		p.publish(p.stateJSONTopic, payload)
+	}
+}
+
+// publish doesn't wait for delivery, render can be called from a message handler
+func (p *LedPart) publish(topic string, payload []byte) {
+	token := p.client.Publish(topic, p.qos, p.retain, payload)
+	go func() {
+		token.Wait()
+		if err := token.Error(); err != nil {
+			zap.S().Errorf("unable to publish led state on topic %v: %v", topic, err)
+		}
+	}()
 }
 
 func (p *LedPart) setDriveMode(m events.DriveMode) {
@@ -100,20 +439,29 @@ func (p *LedPart) onRecord(client mqtt.Client, message mqtt.Message) {
 		return
 	}
 
-	p.muRecord.Lock()
-	defer p.muRecord.Unlock()
-	if p.recordEnabled == switchRecord.GetEnabled() {
+	if !p.setRecord(switchRecord.GetEnabled()) {
 		return
 	}
-	p.recordEnabled = switchRecord.GetEnabled()
-
 	if switchRecord.GetEnabled() {
 		zap.S().Info("record mode enabled")
-		p.led.SetBlink(2)
+		blink := recordBlinkPattern
+		p.SetLayer(SourceRecord, Layer{Priority: PriorityRecord, Blink: &blink, Reason: "record"})
 	} else {
 		zap.S().Info("record mode disabled")
-		p.led.SetBlink(0)
+		p.ClearLayer(SourceRecord)
+	}
+	p.updateColor()
+}
+
+// setRecord returns false if record state is unchanged
+func (p *LedPart) setRecord(enabled bool) bool {
+	p.muRecord.Lock()
+	defer p.muRecord.Unlock()
+	if p.recordEnabled == enabled {
+		return false
 	}
+	p.recordEnabled = enabled
+	return true
 }
 
 func (p *LedPart) setSpeedZone(sz events.SpeedZone) {
@@ -152,67 +500,262 @@ func (p *LedPart) onThrottle(_ mqtt.Client, message mqtt.Message) {
 	p.updateColor()
 }
 
+func (p *LedPart) setTopicValue(topic, value string) {
+	p.muTopics.Lock()
+	defer p.muTopics.Unlock()
+	if p.topicValues == nil {
+		p.topicValues = make(map[string]string)
+	}
+	p.topicValues[topic] = value
+}
+
+// onTopic stores text payload of a topic used by strategy
+func (p *LedPart) onTopic(_ mqtt.Client, message mqtt.Message) {
+	p.setTopicValue(message.Topic(), strings.TrimSpace(string(message.Payload())))
+	p.updateColor()
+}
+
+// strategyTopics returns extra topics needed by strategy
+func (p *LedPart) strategyTopics() []string {
+	p.muStrategy.Lock()
+	defer p.muStrategy.Unlock()
+	if s, ok := p.strategy.(TopicStrategy); ok {
+		return s.Topics()
+	}
+	return nil
+}
+
+func (p *LedPart) onConfig(_ mqtt.Client, message mqtt.Message) {
+	if len(bytes.TrimSpace(message.Payload())) == 0 {
+		return
+	}
+	update, err := ParseConfigUpdate(message.Payload())
+	if err != nil {
+		zap.S().Errorf("invalid led config, keep current one: %v", err)
+		return
+	}
+	mode, cfg, err := update.Apply(p.Config())
+	if err == nil {
+		err = p.Reload(mode, cfg)
+	}
+	if err != nil {
+		zap.S().Errorf("invalid led config, keep current one: %v", err)
+		return
+	}
+	zap.S().Infof("led config reloaded, mode %v", mode)
+}
+
+// onOverride forces led output until command ttl elapses or a clear command is received
+func (p *LedPart) onOverride(_ mqtt.Client, message mqtt.Message) {
+	command, err := ParseOverrideCommand(message.Payload())
+	if err != nil {
+		zap.S().Errorf("invalid led override, ignore it: %v", err)
+		return
+	}
+	if command.Clear {
+		p.ClearLayer(SourceOverride)
+		zap.S().Info("led override cleared")
+		return
+	}
+	l, err := command.Layer()
+	if err != nil {
+		zap.S().Errorf("invalid led override, ignore it: %v", err)
+		return
+	}
+	p.SetLayer(SourceOverride, l)
+	zap.S().Infof("led override set: %s", message.Payload())
+}
+
+// Config returns current led mode and strategy config
+func (p *LedPart) Config() (LedMode, StrategyConfig) {
+	p.muStrategy.Lock()
+	defer p.muStrategy.Unlock()
+	return p.mode, p.config
+}
+
+// Reload validates mode and config, then replaces strategy and renders led from current state. Current
+// strategy is kept on error.
+func (p *LedPart) Reload(mode LedMode, cfg StrategyConfig) error {
+	if cfg.Clock == nil {
+		cfg.Clock = p.clock
+	}
+	s, err := NewStrategy(string(mode), cfg)
+	if err != nil {
+		return err
+	}
+
+	p.muStrategy.Lock()
+	p.mode = mode
+	p.config = cfg
+	p.strategy = s
+	p.muStrategy.Unlock()
+
+	p.muState.Lock()
+	p.stateMode = mode
+	p.muState.Unlock()
+
+	p.updateSubscriptions()
+	p.updateColor()
+	p.republishState()
+	return nil
+}
+
+// updateSubscriptions follows topics needed by strategy once callbacks are registered
+func (p *LedPart) updateSubscriptions() {
+	p.muSubscriptions.Lock()
+	defer p.muSubscriptions.Unlock()
+	if !p.registered {
+		return
+	}
+
+	topics := p.strategyTopics()
+	added, removed := diffTopics(p.subscribed, topics)
+	p.subscribed = topics
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	// Reload can be called from a message handler, where paho doesn't allow to wait for subscriptions
+	go func() {
+		for _, topic := range added {
+			if err := service.RegisterCallback(p.client, topic, p.onTopic); err != nil {
+				zap.S().Errorf("unable to subscribe to rule topic: %v", err)
+			}
+		}
+		if len(removed) > 0 {
+			token := p.client.Unsubscribe(removed...)
+			token.Wait()
+			if token.Error() != nil {
+				zap.S().Errorf("unable to unsubscribe from rule topics %v: %v", removed, token.Error())
+			}
+		}
+	}()
+}
+
+// diffTopics returns topics of next missing from previous and topics of previous missing from next
+func diffTopics(previous, next []string) (added, removed []string) {
+	old := make(map[string]bool, len(previous))
+	for _, t := range previous {
+		old[t] = true
+	}
+	for _, t := range next {
+		if !old[t] {
+			added = append(added, t)
+		}
+		delete(old, t)
+	}
+	for _, t := range previous {
+		if old[t] {
+			removed = append(removed, t)
+		}
+	}
+	return added, removed
+}
+
+func (p *LedPart) onBrightness(_ mqtt.Client, message mqtt.Message) {
+	brightness, err := strconv.ParseFloat(strings.TrimSpace(string(message.Payload())), 64)
+	if err != nil {
+		zap.S().Errorf("unable to parse brightness '%s': %v", message.Payload(), err)
+		return
+	}
+	if p.dimmer == nil {
+		zap.S().Error("led brightness can't be changed without dimmer")
+		return
+	}
+	if err := p.dimmer.SetBrightness(brightness); err != nil {
+		zap.S().Errorf("unable to set led brightness: %v", err)
+		return
+	}
+	zap.S().Infof("led brightness set to %v", brightness)
+}
+
+// updateColor applies output of strategy for current state
 func (p *LedPart) updateColor() {
 	p.muSpeedZone.Lock()
 	defer p.muSpeedZone.Unlock()
 	p.muDriveMode.Lock()
 	defer p.muDriveMode.Unlock()
+	p.muRecord.Lock()
+	defer p.muRecord.Unlock()
 	p.muThrottle.Lock()
 	defer p.muThrottle.Unlock()
+	p.muTopics.Lock()
+	defer p.muTopics.Unlock()
+	p.muStrategy.Lock()
+	defer p.muStrategy.Unlock()
 
-	if p.throttle <= -0.05 {
-		col := led.ColorWhite
-		if p.throttle <= -0.25 {
-			col = led.ColorYellow
-			if p.throttle <= -0.5 {
-				col = led.ColorRed
-				if p.throttle <= -0.75 {
-					col = led.ColorPurple
-				}
-			}
-		}
-		p.led.SetColor(col)
-		return
-	}
-
-	switch p.mode {
-	case LedModeBrake:
-		p.updateBrakeColor()
-	case LedModeSpeedZone:
-		p.updateSpeedZoneColor()
-	}
-}
-
-func (p *LedPart) updateSpeedZoneColor() {
-	switch p.driveMode {
-	case events.DriveMode_USER:
-		p.led.SetColor(led.ColorGreen)
-	case events.DriveMode_COPILOT:
-		p.led.SetColor(led.ColorAqua)
-	case events.DriveMode_PILOT:
-		switch p.speedZone {
-		case events.SpeedZone_UNKNOWN:
-			p.led.SetColor(led.ColorWhite)
-		case events.SpeedZone_SLOW:
-			p.led.SetColor(led.ColorRed)
-		case events.SpeedZone_NORMAL:
-			p.led.SetColor(led.ColorYellow)
-		case events.SpeedZone_FAST:
-			p.led.SetColor(led.ColorBlue)
-		}
+	if p.strategy == nil {
+		p.strategy = p.newStrategy()
+	}
+	out := p.strategy.Output(State{
+		DriveMode: p.driveMode,
+		SpeedZone: p.speedZone,
+		Throttle:  p.throttle,
+		Record:    p.recordEnabled,
+		Topics:    p.topicValues,
+	})
+
+	p.layers().Set(SourceState, Layer{Priority: PriorityState, Color: &out.Color, Blink: &out.Blink, Animation: out.Animation, Reason: out.Reason})
+	if out.Refresh > 0 {
+		p.refreshAfter(out.Refresh)
+	}
+}
+
+// refreshAfter updates color once d elapsed, must be called with state locked
+func (p *LedPart) refreshAfter(d time.Duration) {
+	if p.holdTimer != nil {
+		p.holdTimer.Reset(d)
+		return
 	}
+	p.holdTimer = p.clock.AfterFunc(d, p.refresh)
 }
 
-func (p *LedPart) updateBrakeColor() {
+// refresh updates color when asked by strategy output, until part is stopped
+func (p *LedPart) refresh() {
+	select {
+	case <-p.stopped:
+		return
+	default:
+	}
+	p.updateColor()
+}
+
+// newStrategy builds strategy registered for led mode, brake strategy if mode is unknown
+func (p *LedPart) newStrategy() Strategy {
+	mode := p.mode
+	if mode == "" {
+		mode = LedModeBrake
+	}
+	s, err := NewStrategy(string(mode), p.config)
+	if err != nil {
+		zap.S().Errorf("%v, fallback to %v mode", err, LedModeBrake)
+		s, err = NewStrategy(string(LedModeBrake), p.config)
+	}
+	if err != nil {
+		zap.S().Errorf("%v, fallback to default config", err)
+		s, _ = NewStrategy(string(LedModeBrake), StrategyConfig{Clock: p.config.Clock})
+	}
+	return s
+}
 
-	switch p.driveMode {
-	case events.DriveMode_USER:
-		p.led.SetColor(led.ColorGreen)
-	case events.DriveMode_COPILOT:
-		p.led.SetColor(led.ColorAqua)
-	case events.DriveMode_PILOT:
-		p.led.SetColor(led.ColorBlue)
+// setColor applies color to led with animation, or with a fade if enabled. animated is true if previous
+// color was displayed with an animation
+func (p *LedPart) setColor(color led.Color, anim Animation, animated bool) {
+	if p.animator == nil {
+		p.led.SetColor(color)
+		return
+	}
+	if anim.Enabled() {
+		if err := p.animator.Play(anim.play(color)); err != nil {
+			zap.S().Errorf("unable to play animation %v: %v", anim, err)
+			p.animator.SetColor(color)
+		}
+		return
 	}
+	if animated {
+		// Led keeps color reached by previous animation, fade starts from it
+		p.animator.Stop()
+	}
+	p.animator.FadeTo(color, p.fadeDuration)
 }
 
 func (p *LedPart) registerCallbacks() error {
@@ -236,5 +779,46 @@ func (p *LedPart) registerCallbacks() error {
 		return err
 	}
 
+	if p.onBrightnessTopic != "" {
+		err = service.RegisterCallback(p.client, p.onBrightnessTopic, p.onBrightness)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.onOverrideTopic != "" {
+		err = service.RegisterCallback(p.client, p.onOverrideTopic, p.onOverride)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.registerStrategyCallbacks()
+	if err != nil {
+		return err
+	}
+
+	// Registered last since a retained config reloads strategy topics
+	if p.onConfigTopic != "" {
+		err = service.RegisterCallback(p.client, p.onConfigTopic, p.onConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *LedPart) registerStrategyCallbacks() error {
+	p.muSubscriptions.Lock()
+	defer p.muSubscriptions.Unlock()
+	p.registered = true
+	p.subscribed = p.strategyTopics()
+	for _, topic := range p.subscribed {
+		err := service.RegisterCallback(p.client, topic, p.onTopic)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
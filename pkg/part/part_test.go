@@ -2,10 +2,12 @@ package part
 
 import (
 	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/clock"
 	"github.com/cyrilix/robocar-led/pkg/led"
 	"github.com/cyrilix/robocar-protobuf/go/events"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"google.golang.org/protobuf/proto"
+	"sync"
 	"testing"
 	"time"
 )
@@ -13,6 +15,8 @@ import (
 type fakeLed struct {
 	color led.Color
 	blink bool
+	// blinkCalls counts blink changes
+	blinkCalls int
 }
 
 func (f *fakeLed) SetColor(color led.Color) {
@@ -20,6 +24,7 @@ func (f *fakeLed) SetColor(color led.Color) {
 }
 
 func (f *fakeLed) SetBlink(freq float64) {
+	f.blinkCalls += 1
 	if freq > 0 {
 		f.blink = true
 	} else {
@@ -27,6 +32,79 @@ func (f *fakeLed) SetBlink(freq float64) {
 	}
 }
 
+func (f *fakeLed) SetBlinkPattern(pattern led.BlinkPattern) {
+	f.blinkCalls += 1
+	f.blink = pattern.Enabled()
+}
+
+type fakeToken struct{}
+
+func (f fakeToken) Wait() bool {
+	return true
+}
+
+func (f fakeToken) WaitTimeout(_ time.Duration) bool {
+	return true
+}
+
+func (f fakeToken) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+func (f fakeToken) Error() error {
+	return nil
+}
+
+type publication struct {
+	topic   string
+	qos     byte
+	retain  bool
+	payload []byte
+}
+
+// fakeClient records publications and unsubscriptions, other methods of mqtt.Client aren't implemented
+type fakeClient struct {
+	mqtt.Client
+	mu           sync.Mutex
+	publications []publication
+	unsubscribed []string
+	disconnected bool
+}
+
+func (f *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publications = append(f.publications, publication{topic: topic, qos: qos, retain: retained, payload: payload.([]byte)})
+	return fakeToken{}
+}
+
+func (f *fakeClient) Unsubscribe(topics ...string) mqtt.Token {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unsubscribed = append(f.unsubscribed, topics...)
+	return fakeToken{}
+}
+
+func (f *fakeClient) Disconnect(_ uint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disconnected = true
+}
+
+func (f *fakeClient) published(topic string) []publication {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []publication
+	for _, p := range f.publications {
+		if p.topic == topic {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
 func TestLedPart_OnDriveMode(t *testing.T) {
 	l := fakeLed{}
 	p := LedPart{led: &l, speedZone: events.SpeedZone_FAST}
@@ -43,7 +121,6 @@ func TestLedPart_OnDriveMode(t *testing.T) {
 
 	for _, c := range cases {
 		p.onDriveMode(nil, c.msg)
-		time.Sleep(1 * time.Millisecond)
 		var msg events.DriveModeMessage
 		err := proto.Unmarshal(c.msg.Payload(), &msg)
 		if err != nil {
@@ -102,7 +179,6 @@ func TestLedPart_OnSpeedZone(t *testing.T) {
 
 	for _, c := range cases {
 		p.onSpeedZone(nil, c.msg)
-		time.Sleep(1 * time.Millisecond)
 		var msg events.SpeedZoneMessage
 		err := proto.Unmarshal(c.msg.Payload(), &msg)
 		if err != nil {
@@ -164,7 +240,6 @@ func TestLedPart_OnThrottle(t *testing.T) {
 			p := LedPart{led: &l, mode: LedModeBrake, driveMode: events.DriveMode_PILOT}
 
 			p.onThrottle(nil, c.msg)
-			time.Sleep(1 * time.Millisecond)
 			var msg events.ThrottleMessage
 			err := proto.Unmarshal(c.msg.Payload(), &msg)
 			if err != nil {
@@ -177,3 +252,96 @@ func TestLedPart_OnThrottle(t *testing.T) {
 		})
 	}
 }
+
+func TestLedPart_FadeDuration(t *testing.T) {
+	l := led.NewSimLed()
+	clk := clock.NewFake(time.Now())
+	p := NewPart(nil, l, "drive", "record", "speedzone", "throttle", LedModeBrake,
+		WithFadeDuration(50*time.Millisecond), WithClock(clk))
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	for i := 0; i < 3; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(led.DefaultFrameInterval)
+	}
+	p.animator.Wait()
+
+	if l.Color() != led.ColorGreen {
+		t.Errorf("color after fade: %v, wants %v", l.Color(), led.ColorGreen)
+	}
+	evts := l.Events()
+	if len(evts) < 3 {
+		t.Errorf("led switched to color without fade: %v", evts)
+	}
+	for _, e := range evts[:len(evts)-1] {
+		if e.Color.Green <= 0 || e.Color.Green >= 255 || e.Color.Red != 0 || e.Color.Blue != 0 {
+			t.Errorf("invalid intermediate color %v", e.Color)
+		}
+	}
+}
+
+func TestLedPart_OnBrightness(t *testing.T) {
+	l := led.NewSimLed()
+	p := NewPart(nil, l, "drive", "record", "speedzone", "throttle", LedModeBrake, WithBrightness(0.5))
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	if expected := (led.Color{Green: 128}); l.Color() != expected {
+		t.Errorf("color with initial brightness: %v, wants %v", l.Color(), expected)
+	}
+
+	cases := []struct {
+		name     string
+		payload  string
+		expected led.Color
+	}{
+		{"dim", "0.2", led.Color{Green: 51}},
+		{"invalid value", "bright", led.Color{Green: 51}},
+		{"out of range", "2", led.Color{Green: 51}},
+		{"full", " 1\n", led.ColorGreen},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p.onBrightness(nil, testtools.NewFakeMessage("brightness", []byte(c.payload)))
+			if l.Color() != c.expected {
+				t.Errorf("color: %v, wants %v", l.Color(), c.expected)
+			}
+		})
+	}
+
+	// Brightness persists across color changes
+	_ = p.dimmer.SetBrightness(0.2)
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: -1.}))
+	if expected := (led.Color{Red: 51, Blue: 51}); l.Color() != expected {
+		t.Errorf("color after change: %v, wants %v", l.Color(), expected)
+	}
+}
+
+func TestLedPart_ThrottleGradient(t *testing.T) {
+	l := fakeLed{}
+	p := LedPart{led: &l, mode: LedModeThrottleGradient, driveMode: events.DriveMode_PILOT}
+
+	cases := []struct {
+		throttle float32
+		color    led.Color
+	}{
+		{-1, led.ColorBlue},
+		{-0.5, led.Color{Blue: 127}},
+		{0, led.ColorBlack},
+		{0.05, led.ColorGreen},
+		{1, led.ColorRed},
+	}
+	for _, c := range cases {
+		p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: c.throttle}))
+		if l.color != c.color {
+			t.Errorf("throttle %v: %v, wants %v", c.throttle, l.color, c.color)
+		}
+	}
+
+	gradient, _ := led.ParseGradient("0=black,1=white")
+	p = LedPart{led: &l, mode: LedModeThrottleGradient, driveMode: events.DriveMode_PILOT}
+	WithThrottleGradient(gradient)(&p)
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: 0.5}))
+	if expected := (led.Color{Red: 128, Green: 128, Blue: 128}); l.color != expected {
+		t.Errorf("color with custom gradient: %v, wants %v", l.color, expected)
+	}
+}
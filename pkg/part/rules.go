@@ -0,0 +1,356 @@
+package part
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RuleColorBrake is the rule color replaced by current brake ladder color, black when not braking
+const RuleColorBrake = "brake"
+
+// Rule yields Color and Blink when all its conditions match car state, an empty condition matches
+// any state
+type Rule struct {
+	Name string `json:"name,omitempty"`
+
+	// DriveModes and SpeedZones are protobuf enum names (ex: "pilot", "fast")
+	DriveModes []string       `json:"drive_modes,omitempty"`
+	SpeedZones []string       `json:"speed_zones,omitempty"`
+	Throttle   *ThrottleRange `json:"throttle,omitempty"`
+	Record     *bool          `json:"record,omitempty"`
+	// Braking matches brake ladder state, brake hold and deceleration included
+	Braking *bool `json:"braking,omitempty"`
+	// Topics matches last text payload received on each topic
+	Topics map[string]string `json:"topics,omitempty"`
+
+	// Color is a color value, a palette color name or RuleColorBrake
+	Color string            `json:"color,omitempty"`
+	Blink *led.BlinkPattern `json:"blink,omitempty"`
+	// Animation is played to display Color (ex: "breathe 2s")
+	Animation *Animation `json:"animation,omitempty"`
+}
+
+// ThrottleRange matches throttle between Min and Max included, a nil bound is unlimited
+type ThrottleRange struct {
+	Min *float32 `json:"min,omitempty"`
+	Max *float32 `json:"max,omitempty"`
+}
+
+func (r *ThrottleRange) match(throttle float32) bool {
+	if r == nil {
+		return true
+	}
+	return (r.Min == nil || throttle >= *r.Min) && (r.Max == nil || throttle <= *r.Max)
+}
+
+// Rules are evaluated in order, the first matching rule gives color and blink. Color is unchanged if
+// this rule has no color or if no rule matches, led doesn't blink if it has no blink.
+type Rules []Rule
+
+var (
+	brakeRule   = Rule{Name: "brake", Braking: boolRef(true), Color: RuleColorBrake}
+	userRule    = Rule{Name: "user", DriveModes: []string{"user"}, Color: "user"}
+	copilotRule = Rule{Name: "copilot", DriveModes: []string{"copilot"}, Color: "copilot"}
+)
+
+// BrakeRules reproduce LedModeBrake: brake ladder, then drive mode color
+var BrakeRules = Rules{
+	brakeRule,
+	userRule,
+	copilotRule,
+	{Name: "pilot", DriveModes: []string{"pilot"}, Color: "pilot"},
+}
+
+// SpeedZoneRules reproduce LedModeSpeedZone: brake ladder, then speed zone color in pilot mode
+var SpeedZoneRules = Rules{
+	brakeRule,
+	userRule,
+	copilotRule,
+	{Name: "unknown speed zone", DriveModes: []string{"pilot"}, SpeedZones: []string{"unknown"}, Color: "speed_zone_unknown"},
+	{Name: "slow speed zone", DriveModes: []string{"pilot"}, SpeedZones: []string{"slow"}, Color: "speed_zone_slow"},
+	{Name: "normal speed zone", DriveModes: []string{"pilot"}, SpeedZones: []string{"normal"}, Color: "speed_zone_normal"},
+	{Name: "fast speed zone", DriveModes: []string{"pilot"}, SpeedZones: []string{"fast"}, Color: "speed_zone_fast"},
+}
+
+func boolRef(b bool) *bool {
+	return &b
+}
+
+// LoadRules reads an array of rules from a json file, or from a yaml file if path ends with .yaml or
+// .yml:
+//
+//	[{"name": "reverse", "throttle": {"max": -0.1}, "color": "white", "blink": "4hz"}, ...]
+func LoadRules(path string) (Rules, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rules file: %v", err)
+	}
+	var items []json.RawMessage
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		items, err = yamlRules(content)
+	default:
+		err = json.Unmarshal(content, &items)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rules file '%v', must be an array of rules: %v", path, err)
+	}
+	rules := make(Rules, len(items))
+	for i, item := range items {
+		decoder := json.NewDecoder(bytes.NewReader(item))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rules[i]); err != nil {
+			return nil, fmt.Errorf("invalid rules file '%v', rule #%v: %v", path, i+1, err)
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules file '%v', %v", path, err)
+	}
+	return rules, nil
+}
+
+// yamlRules converts each rule of a yaml array to json, so that yaml rules are checked like json rules
+func yamlRules(content []byte) ([]json.RawMessage, error) {
+	var values []interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, err
+	}
+	items := make([]json.RawMessage, len(values))
+	for i, v := range values {
+		item, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("rule #%v: %v", i+1, err)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// Validate checks each rule, error names the first invalid rule
+func (r Rules) Validate() error {
+	if len(r) == 0 {
+		return fmt.Errorf("no rule defined")
+	}
+	_, err := r.compile(&DefaultPalette)
+	return err
+}
+
+// Topics lists topics used by rule conditions
+func (r Rules) Topics() []string {
+	topics := map[string]bool{}
+	for _, rule := range r {
+		for t := range rule.Topics {
+			topics[t] = true
+		}
+	}
+	names := make([]string, 0, len(topics))
+	for t := range topics {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r Rules) compile(palette *Palette) ([]compiledRule, error) {
+	compiled := make([]compiledRule, len(r))
+	for i, rule := range r {
+		c, err := compileRule(rule, palette)
+		if err != nil {
+			if rule.Name != "" {
+				return nil, fmt.Errorf("rule #%v '%v': %v", i+1, rule.Name, err)
+			}
+			return nil, fmt.Errorf("rule #%v: %v", i+1, err)
+		}
+		compiled[i] = c
+	}
+	return compiled, nil
+}
+
+type compiledRule struct {
+	Rule
+	driveModes map[events.DriveMode]bool
+	speedZones map[events.SpeedZone]bool
+	hasColor   bool
+	brakeColor bool
+	color      led.Color
+}
+
+func compileRule(r Rule, palette *Palette) (compiledRule, error) {
+	c := compiledRule{Rule: r}
+	if r.Color == "" && r.Blink == nil {
+		return c, fmt.Errorf("rule without color nor blink")
+	}
+	if r.Animation != nil && r.Color == "" {
+		return c, fmt.Errorf("rule with animation but without color")
+	}
+	if r.Throttle != nil && r.Throttle.Min != nil && r.Throttle.Max != nil && *r.Throttle.Min > *r.Throttle.Max {
+		return c, fmt.Errorf("throttle min %v greater than max %v", *r.Throttle.Min, *r.Throttle.Max)
+	}
+	for t := range r.Topics {
+		if strings.TrimSpace(t) == "" {
+			return c, fmt.Errorf("empty topic name")
+		}
+	}
+
+	if len(r.DriveModes) > 0 {
+		c.driveModes = make(map[events.DriveMode]bool, len(r.DriveModes))
+		for _, m := range r.DriveModes {
+			v, ok := events.DriveMode_value[strings.ToUpper(strings.TrimSpace(m))]
+			if !ok {
+				return c, fmt.Errorf("unknown drive mode '%v'", m)
+			}
+			c.driveModes[events.DriveMode(v)] = true
+		}
+	}
+	if len(r.SpeedZones) > 0 {
+		c.speedZones = make(map[events.SpeedZone]bool, len(r.SpeedZones))
+		for _, z := range r.SpeedZones {
+			v, ok := events.SpeedZone_value[strings.ToUpper(strings.TrimSpace(z))]
+			if !ok {
+				return c, fmt.Errorf("unknown speed zone '%v'", z)
+			}
+			c.speedZones[events.SpeedZone(v)] = true
+		}
+	}
+
+	if r.Color != "" {
+		c.hasColor = true
+		name := strings.TrimSpace(r.Color)
+		if name == RuleColorBrake {
+			c.brakeColor = true
+		} else if col, ok := palette.colors()[name]; ok {
+			c.color = *col
+		} else {
+			col, err := led.ParseColor(name)
+			if err != nil {
+				return c, fmt.Errorf("color '%v' is neither a palette color nor a valid color: %v", r.Color, err)
+			}
+			c.color = col
+		}
+	}
+	return c, nil
+}
+
+// label names rule at index i in reasons
+func (c *compiledRule) label(i int) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("rule #%v", i+1)
+}
+
+func (c *compiledRule) match(s State, braking bool) bool {
+	if c.driveModes != nil && !c.driveModes[s.DriveMode] {
+		return false
+	}
+	if c.speedZones != nil && !c.speedZones[s.SpeedZone] {
+		return false
+	}
+	if !c.Throttle.match(s.Throttle) {
+		return false
+	}
+	if c.Record != nil && *c.Record != s.Record {
+		return false
+	}
+	if c.Braking != nil && *c.Braking != braking {
+		return false
+	}
+	for topic, value := range c.Topics {
+		if s.Topics[topic] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// rulesStrategy renders first matching rule, brake state comes from brake ladder
+type rulesStrategy struct {
+	rules  []compiledRule
+	topics []string
+	brake  *brakeLadder
+	light  *brakeLight
+
+	last          led.Color
+	lastAnimation Animation
+	lastReason    string
+}
+
+func newRulesStrategy(cfg StrategyConfig, rules Rules) (Strategy, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rule defined")
+	}
+	palette := cfg.palette()
+	compiled, err := rules.compile(palette)
+	if err != nil {
+		return nil, err
+	}
+	levels := cfg.BrakeLevels
+	if levels == nil {
+		levels = palette.BrakeLevels()
+	}
+	if err := ValidateBrakeLevels(levels); err != nil {
+		return nil, err
+	}
+	window := cfg.DecelerationWindow
+	if window == 0 {
+		window = DefaultDecelerationWindow
+	}
+	clk := cfg.clock()
+	return &rulesStrategy{
+		rules:      compiled,
+		topics:     rules.Topics(),
+		brake:      newBrakeLadder(levels, cfg.BrakeHysteresis, cfg.BrakeMinHold, clk),
+		light:      newBrakeLight(cfg.BrakeHold, cfg.DecelerationDrop, window, clk),
+		last:       led.ColorBlack,
+		lastReason: "no matching rule",
+	}, nil
+}
+
+// Topics implements TopicStrategy
+func (r *rulesStrategy) Topics() []string {
+	return r.topics
+}
+
+func (r *rulesStrategy) Output(s State) Output {
+	var out Output
+	brakeColor, braking := r.brake.update(s.Throttle)
+	if r.light.enabled() {
+		r.light.addThrottle(s.Throttle)
+		brakeColor, braking, out.Refresh = r.light.update(brakeColor, braking, r.brake.levels[0].Color)
+	}
+
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !rule.match(s, braking) {
+			continue
+		}
+		if rule.hasColor {
+			if rule.brakeColor {
+				r.last = brakeColor
+			} else {
+				r.last = rule.color
+			}
+			r.lastAnimation = Animation{}
+			if rule.Animation != nil {
+				r.lastAnimation = *rule.Animation
+			}
+		}
+		if rule.Blink != nil {
+			out.Blink = *rule.Blink
+		}
+		r.lastReason = rule.label(i)
+		break
+	}
+	out.Color = r.last
+	out.Animation = r.lastAnimation
+	out.Reason = r.lastReason
+	return out
+}
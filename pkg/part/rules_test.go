@@ -0,0 +1,177 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`[
+  {"name": "reverse", "throttle": {"max": -0.1}, "color": "white", "blink": "4hz"},
+  {"name": "pilot", "drive_modes": ["pilot", "copilot"], "color": "pilot"}
+]`), 0644); err != nil {
+		t.Fatalf("unable to write rules: %v", err)
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("unable to load rules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "reverse" || *rules[0].Throttle.Max != -0.1 || *rules[0].Blink != led.BlinkFrequency(4) {
+		t.Errorf("invalid rules: %+v", rules)
+	}
+
+	cases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"not an array", `{"color": "red"}`, "array of rules"},
+		{"unknown field", `[{"color": "red"}, {"colour": "red"}]`, "rule #2"},
+		{"unknown drive mode", `[{"name": "auto", "drive_modes": ["auto"], "color": "red"}]`, "rule #1 'auto': unknown drive mode"},
+		{"invalid color", `[{"color": "red"}, {"name": "bad", "color": "reddish"}]`, "rule #2 'bad'"},
+		{"invalid blink", `[{"blink": "often"}]`, "rule #1"},
+		{"empty output", `[{"record": true}]`, "rule #1: rule without color nor blink"},
+		{"invalid throttle range", `[{"throttle": {"min": 1, "max": 0}, "color": "red"}]`, "throttle min"},
+		{"invalid animation", `[{"color": "red", "animation": "pulse 1s"}]`, "rule #1: unknown animation"},
+		{"animation without color", `[{"blink": "2hz", "animation": "breathe 1s"}]`, "rule #1: rule with animation but without color"},
+		{"no rule", `[]`, "no rule"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatalf("unable to write rules: %v", err)
+			}
+			_, err := LoadRules(path)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("error: %v, wants error containing '%v'", err, c.err)
+			}
+		})
+	}
+}
+
+func TestLoadRules_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(`
+- name: reverse
+  throttle: {max: -0.1}
+  color: white
+  blink: 4hz
+- name: pilot
+  drive_modes: [pilot, copilot]
+  color: "#0000FF"
+  animation: breathe 2s
+`), 0644); err != nil {
+		t.Fatalf("unable to write rules: %v", err)
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("unable to load rules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "reverse" || *rules[0].Throttle.Max != -0.1 || *rules[0].Blink != led.BlinkFrequency(4) {
+		t.Errorf("invalid rules: %+v", rules)
+	}
+	if len(rules) == 2 && (rules[1].Color != "#0000FF" || len(rules[1].DriveModes) != 2 ||
+		rules[1].Animation == nil || *rules[1].Animation != (Animation{Kind: AnimationBreathe, Duration: 2 * time.Second})) {
+		t.Errorf("invalid rule: %+v", rules[1])
+	}
+
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{"not an array", "rules.yaml", `color: red`, "array of rules"},
+		{"invalid yaml", "rules.yaml", `[{color: red`, "unable to parse rules file"},
+		{"unknown field", "rules.yml", "- color: red\n- colour: red\n", "rule #2"},
+		{"invalid animation", "rules.yml", "- color: red\n  animation: breathe\n", "rule #1"},
+		{"invalid rule", "rules.yaml", "- name: auto\n  drive_modes: [auto]\n  color: red\n", "rule #1 'auto': unknown drive mode"},
+		{"yaml is not read as json", "rules.json", "- color: red\n", "array of rules"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), c.file)
+			if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatalf("unable to write rules: %v", err)
+			}
+			_, err := LoadRules(path)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("error: %v, wants error containing '%v'", err, c.err)
+			}
+		})
+	}
+}
+
+func TestRulesStrategy(t *testing.T) {
+	min := float32(0.5)
+	breathe := Animation{Kind: AnimationBreathe, Duration: 2 * time.Second}
+	rules := Rules{
+		{Name: "record", Record: boolRef(true), Blink: &recordBlinkPattern},
+		{Name: "fast", Throttle: &ThrottleRange{Min: &min}, Color: "red"},
+		{Name: "lap", Topics: map[string]string{"lap": "last"}, Color: "yellow", Blink: &led.BlinkPattern{On: time.Second, Off: time.Second}},
+		{Name: "user", DriveModes: []string{"user"}, Color: "user"},
+		{Name: "copilot", DriveModes: []string{"copilot"}, Color: "copilot", Animation: &breathe},
+	}
+	s, err := NewStrategy(string(LedModeRules), StrategyConfig{Rules: rules})
+	if err != nil {
+		t.Fatalf("unable to build rules strategy: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		state State
+		out   Output
+	}{
+		{"no match", State{DriveMode: events.DriveMode_PILOT}, Output{Color: led.ColorBlack, Reason: "no matching rule"}},
+		{"drive mode", State{DriveMode: events.DriveMode_USER}, Output{Color: led.ColorGreen, Reason: "user"}},
+		{"first match wins", State{DriveMode: events.DriveMode_USER, Throttle: 0.8}, Output{Color: led.ColorRed, Reason: "fast"}},
+		{"first match gives blink", State{DriveMode: events.DriveMode_USER, Throttle: 0.8, Topics: map[string]string{"lap": "last"}}, Output{Color: led.ColorRed, Reason: "fast"}},
+		{"rule without color keeps color", State{DriveMode: events.DriveMode_USER, Record: true, Topics: map[string]string{"lap": "last"}}, Output{Color: led.ColorRed, Blink: recordBlinkPattern, Reason: "record"}},
+		{"topic", State{Topics: map[string]string{"lap": "last"}}, Output{Color: led.ColorYellow, Blink: led.BlinkPattern{On: time.Second, Off: time.Second}, Reason: "lap"}},
+		{"no match keeps color", State{Topics: map[string]string{"lap": "first"}}, Output{Color: led.ColorYellow, Reason: "lap"}},
+		{"animation", State{DriveMode: events.DriveMode_COPILOT}, Output{Color: led.ColorAqua, Animation: breathe, Reason: "copilot"}},
+		{"no match keeps animation", State{DriveMode: events.DriveMode_PILOT}, Output{Color: led.ColorAqua, Animation: breathe, Reason: "copilot"}},
+		{"color without animation", State{DriveMode: events.DriveMode_USER}, Output{Color: led.ColorGreen, Reason: "user"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if out := s.Output(c.state); out != c.out {
+				t.Errorf("output: %v, wants %v", out, c.out)
+			}
+		})
+	}
+
+	if topics := s.(TopicStrategy).Topics(); len(topics) != 1 || topics[0] != "lap" {
+		t.Errorf("topics: %v, wants [lap]", topics)
+	}
+
+	if _, err := NewStrategy(string(LedModeRules), StrategyConfig{}); err == nil {
+		t.Errorf("rules mode without rules must fail")
+	}
+}
+
+func TestLedPart_OnTopic(t *testing.T) {
+	l := fakeLed{}
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeRules, WithRules(Rules{
+		{Topics: map[string]string{"battery": "low"}, Color: "red", Blink: &recordBlinkPattern},
+		{Color: "green"},
+	}))
+
+	p.onTopic(nil, testtools.NewFakeMessage("battery", []byte("ok")))
+	if l.color != led.ColorGreen || l.blink {
+		t.Errorf("color: (%v, blink=%v), wants (%v, false)", l.color, l.blink, led.ColorGreen)
+	}
+	p.onTopic(nil, testtools.NewFakeMessage("battery", []byte("low\n")))
+	if l.color != led.ColorRed || !l.blink {
+		t.Errorf("color: (%v, blink=%v), wants (%v, true)", l.color, l.blink, led.ColorRed)
+	}
+}
@@ -0,0 +1,33 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-led/pkg/ledevents"
+)
+
+// LedState is led output published on state topics, protobuf encoding is ledevents.LedStateMessage
+type LedState struct {
+	Color led.Color        `json:"color"`
+	Blink led.BlinkPattern `json:"blink"`
+	Mode  LedMode          `json:"mode"`
+	// Source is the layer source that gives color, SourceState for car state
+	Source string `json:"source"`
+	// Reason describes what produced color (ex: rule name)
+	Reason string `json:"reason"`
+}
+
+// Proto returns state as protobuf message published on state topic
+func (s LedState) Proto() *ledevents.LedStateMessage {
+	return &ledevents.LedStateMessage{
+		Red:          int32(s.Color.Red),
+		Green:        int32(s.Color.Green),
+		Blue:         int32(s.Color.Blue),
+		BlinkOnMs:    s.Blink.On.Milliseconds(),
+		BlinkOffMs:   s.Blink.Off.Milliseconds(),
+		BlinkCount:   int32(s.Blink.Count),
+		BlinkPauseMs: s.Blink.Pause.Milliseconds(),
+		Mode:         string(s.Mode),
+		Source:       s.Source,
+		Reason:       s.Reason,
+	}
+}
@@ -0,0 +1,92 @@
+package part
+
+import (
+	"encoding/json"
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-led/pkg/ledevents"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"google.golang.org/protobuf/proto"
+	"testing"
+	"time"
+)
+
+func TestLedState_Proto(t *testing.T) {
+	state := LedState{
+		Color:  led.Color{Red: 12, Green: 255, Blue: 1},
+		Blink:  led.BlinkPattern{On: 100 * time.Millisecond, Off: 200 * time.Millisecond, Count: 3, Pause: time.Second},
+		Mode:   LedModeRules,
+		Source: SourceState,
+		Reason: "lap",
+	}
+	expected := &ledevents.LedStateMessage{Red: 12, Green: 255, Blue: 1, BlinkOnMs: 100, BlinkOffMs: 200, BlinkCount: 3,
+		BlinkPauseMs: 1000, Mode: "rules", Source: "state", Reason: "lap"}
+	if msg := state.Proto(); !proto.Equal(msg, expected) {
+		t.Errorf("message: %v, wants %v", msg, expected)
+	}
+}
+
+func TestLedState_JSON(t *testing.T) {
+	payload, err := json.Marshal(LedState{Color: led.ColorRed, Blink: led.BlinkFrequency(2), Mode: LedModeBrake, Source: SourceState, Reason: "brake"})
+	if err != nil {
+		t.Fatalf("unable to marshal led state: %v", err)
+	}
+	expected := `{"color":"#FF0000","blink":"500ms,500ms","mode":"brake","source":"state","reason":"brake"}`
+	if string(payload) != expected {
+		t.Errorf("json: %s, wants %s", payload, expected)
+	}
+}
+
+func TestLedPart_PublishState(t *testing.T) {
+	client := fakeClient{}
+	l := fakeLed{}
+	p := NewPart(&client, &l, "drive", "record", "speedzone", "throttle", LedModeBrake,
+		WithStateTopic("led/state"), WithStateJSONTopic("led/state/json"), WithPublishOptions(1, true))
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_USER}))
+	p.onRecord(nil, testtools.NewFakeMessageFromProtobuf("record", &events.SwitchRecordMessage{Enabled: true}))
+	// Unchanged output isn't published
+	p.onSpeedZone(nil, testtools.NewFakeMessageFromProtobuf("speedzone", &events.SpeedZoneMessage{SpeedZone: events.SpeedZone_FAST}))
+
+	published := client.published("led/state")
+	if len(published) != 2 {
+		t.Fatalf("%v states published, wants 2", len(published))
+	}
+	if published[1].qos != 1 || !published[1].retain {
+		t.Errorf("publish options: (qos=%v, retain=%v), wants (1, true)", published[1].qos, published[1].retain)
+	}
+	var msg ledevents.LedStateMessage
+	if err := proto.Unmarshal(published[1].payload, &msg); err != nil {
+		t.Fatalf("unable to decode led state: %v", err)
+	}
+	expected := LedState{Color: led.ColorGreen, Blink: recordBlinkPattern, Mode: LedModeBrake, Source: SourceState, Reason: "user"}
+	if !proto.Equal(&msg, expected.Proto()) {
+		t.Errorf("state: %v, wants %v", &msg, expected.Proto())
+	}
+	if n := len(client.published("led/state/json")); n != 2 {
+		t.Errorf("%v json states published, wants 2", n)
+	}
+
+	p.SetLayer("fault", Layer{Priority: 100, Color: colorRef(led.ColorRed), Reason: "lost camera"})
+	published = client.published("led/state/json")
+	var jsonState LedState
+	if err := json.Unmarshal(published[len(published)-1].payload, &jsonState); err != nil {
+		t.Fatalf("unable to decode json led state: %v", err)
+	}
+	expected = LedState{Color: led.ColorRed, Blink: recordBlinkPattern, Mode: LedModeBrake, Source: "fault", Reason: "lost camera"}
+	if jsonState != expected {
+		t.Errorf("json state: %+v, wants %+v", jsonState, expected)
+	}
+
+	// Mode change is published even if led output is unchanged
+	if err := p.Reload(LedModeSpeedZone, StrategyConfig{}); err != nil {
+		t.Fatalf("unable to reload: %v", err)
+	}
+	published = client.published("led/state")
+	if err := proto.Unmarshal(published[len(published)-1].payload, &msg); err != nil {
+		t.Fatalf("unable to decode led state: %v", err)
+	}
+	if msg.Mode != string(LedModeSpeedZone) || msg.Red != 255 || msg.Green != 0 || msg.Blue != 0 {
+		t.Errorf("state after reload: %v, wants red in speedzone mode", &msg)
+	}
+}
@@ -0,0 +1,185 @@
+package part
+
+import (
+	"fmt"
+	"github.com/cyrilix/robocar-led/pkg/clock"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is a snapshot of car state given to strategies
+type State struct {
+	DriveMode events.DriveMode
+	SpeedZone events.SpeedZone
+	Throttle  float32
+	Record    bool
+	// Topics holds last text payload received on topics listed by a TopicStrategy
+	Topics map[string]string
+}
+
+// Output is led rendering wanted by a strategy
+type Output struct {
+	Color led.Color
+	Blink led.BlinkPattern
+	// Animation is played to display Color
+	Animation Animation
+	// Reason describes what produced output (ex: rule name), published with led state
+	Reason string
+	// Refresh asks to compute output again after this duration, even if state doesn't change
+	Refresh time.Duration
+}
+
+// Strategy computes led output from car state, it's called on each state change
+type Strategy interface {
+	Output(s State) Output
+}
+
+// TopicStrategy is implemented by strategies that need payloads of extra topics in State.Topics
+type TopicStrategy interface {
+	Strategy
+	Topics() []string
+}
+
+// StrategyConfig holds settings given to strategy factories, zero values select defaults
+type StrategyConfig struct {
+	Palette          *Palette
+	BrakeLevels      []BrakeLevel
+	BrakeHysteresis  float32
+	BrakeMinHold     time.Duration
+	BrakeHold        time.Duration
+	DecelerationDrop float32
+	// DecelerationWindow is DefaultDecelerationWindow if 0
+	DecelerationWindow time.Duration
+	ThrottleGradient   led.Gradient
+	// Rules are used by LedModeRules
+	Rules Rules
+	Clock clock.Clock
+}
+
+// Validate checks numeric settings, it's called by NewStrategy before strategy factory
+func (c StrategyConfig) Validate() error {
+	for _, v := range []struct {
+		name  string
+		value float32
+	}{
+		{"brake hysteresis", c.BrakeHysteresis},
+		{"deceleration drop", c.DecelerationDrop},
+	} {
+		if v.value < 0 {
+			return fmt.Errorf("invalid %v %v, must not be negative", v.name, v.value)
+		}
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"brake min hold", c.BrakeMinHold},
+		{"brake hold", c.BrakeHold},
+		{"deceleration window", c.DecelerationWindow},
+	} {
+		if d.value < 0 {
+			return fmt.Errorf("invalid %v %v, must not be negative", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+func (c StrategyConfig) palette() *Palette {
+	if c.Palette == nil {
+		return &DefaultPalette
+	}
+	return c.Palette
+}
+
+func (c StrategyConfig) clock() clock.Clock {
+	if c.Clock == nil {
+		return clock.New()
+	}
+	return c.Clock
+}
+
+// StrategyFactory builds a new strategy instance from config
+type StrategyFactory func(cfg StrategyConfig) (Strategy, error)
+
+var (
+	muStrategies sync.RWMutex
+	strategies   = map[string]StrategyFactory{
+		string(LedModeBrake):            newBrakeStrategy,
+		string(LedModeSpeedZone):        newSpeedZoneStrategy,
+		string(LedModeThrottleGradient): newThrottleGradientStrategy,
+		string(LedModeRules):            newConfiguredRulesStrategy,
+	}
+)
+
+// RegisterStrategy makes strategy available under name, it replaces any strategy with same name
+func RegisterStrategy(name string, factory StrategyFactory) {
+	muStrategies.Lock()
+	defer muStrategies.Unlock()
+	strategies[strings.ToLower(name)] = factory
+}
+
+// StrategyNames lists registered strategies
+func StrategyNames() []string {
+	muStrategies.RLock()
+	defer muStrategies.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for n := range strategies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStrategy builds strategy registered with name
+func NewStrategy(name string, cfg StrategyConfig) (Strategy, error) {
+	muStrategies.RLock()
+	factory, ok := strategies[strings.ToLower(name)]
+	muStrategies.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown led mode '%v', available modes: %v", name, strings.Join(StrategyNames(), ", "))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to init led mode '%v': %v", name, err)
+	}
+	s, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init led mode '%v': %v", name, err)
+	}
+	return s, nil
+}
+
+func newBrakeStrategy(cfg StrategyConfig) (Strategy, error) {
+	return newRulesStrategy(cfg, BrakeRules)
+}
+
+func newSpeedZoneStrategy(cfg StrategyConfig) (Strategy, error) {
+	return newRulesStrategy(cfg, SpeedZoneRules)
+}
+
+func newConfiguredRulesStrategy(cfg StrategyConfig) (Strategy, error) {
+	return newRulesStrategy(cfg, cfg.Rules)
+}
+
+// throttleGradientStrategy displays throttle as a continuous color gradient
+type throttleGradientStrategy struct {
+	gradient led.Gradient
+}
+
+func newThrottleGradientStrategy(cfg StrategyConfig) (Strategy, error) {
+	gradient := cfg.ThrottleGradient
+	if gradient == nil {
+		gradient = DefaultThrottleGradient
+	}
+	return &throttleGradientStrategy{gradient: gradient}, nil
+}
+
+func (t *throttleGradientStrategy) Output(s State) Output {
+	return Output{
+		Color:  t.gradient.At(float64(s.Throttle)),
+		Reason: "throttle gradient",
+	}
+}
@@ -0,0 +1,117 @@
+package part
+
+import (
+	"github.com/cyrilix/robocar-base/testtools"
+	"github.com/cyrilix/robocar-led/pkg/led"
+	"github.com/cyrilix/robocar-protobuf/go/events"
+	"testing"
+	"time"
+)
+
+func TestStrategyNames(t *testing.T) {
+	names := map[string]bool{}
+	for _, n := range StrategyNames() {
+		names[n] = true
+	}
+	for _, m := range []LedMode{LedModeBrake, LedModeSpeedZone, LedModeThrottleGradient, LedModeRules} {
+		if !names[string(m)] {
+			t.Errorf("strategy %v not registered: %v", m, StrategyNames())
+		}
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	if _, err := NewStrategy("unknown", StrategyConfig{}); err == nil {
+		t.Errorf("no error for unknown strategy")
+	}
+	for _, cfg := range []StrategyConfig{
+		{BrakeHysteresis: -3},
+		{BrakeMinHold: -time.Second},
+		{BrakeHold: -5 * time.Second},
+		{DecelerationDrop: -1},
+		{DecelerationWindow: -time.Millisecond},
+	} {
+		if _, err := NewStrategy(string(LedModeBrake), cfg); err == nil {
+			t.Errorf("no error for invalid config %+v", cfg)
+		}
+	}
+	for _, levels := range [][]BrakeLevel{{}, {{-0.5, led.ColorRed}, {-0.1, led.ColorWhite}}} {
+		if _, err := NewStrategy(string(LedModeBrake), StrategyConfig{BrakeLevels: levels, BrakeHold: time.Second}); err == nil {
+			t.Errorf("no error for invalid brake levels %v", levels)
+		}
+	}
+
+	s, err := NewStrategy("SpeedZone", StrategyConfig{})
+	if err != nil {
+		t.Fatalf("unable to build speedzone strategy: %v", err)
+	}
+	cases := []struct {
+		name  string
+		state State
+		out   Output
+	}{
+		{"pilot", State{DriveMode: events.DriveMode_PILOT, SpeedZone: events.SpeedZone_SLOW}, Output{Color: led.ColorRed, Reason: "slow speed zone"}},
+		{"invalid drive mode keeps color", State{DriveMode: events.DriveMode_INVALID}, Output{Color: led.ColorRed, Reason: "slow speed zone"}},
+		{"record", State{DriveMode: events.DriveMode_USER, Record: true}, Output{Color: led.ColorGreen, Reason: "user"}},
+		{"brake", State{DriveMode: events.DriveMode_USER, Throttle: -1}, Output{Color: led.ColorPurple, Reason: "brake"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if out := s.Output(c.state); out != c.out {
+				t.Errorf("output: %v, wants %v", out, c.out)
+			}
+		})
+	}
+}
+
+type fixedStrategy struct {
+	color led.Color
+}
+
+func (f fixedStrategy) Output(s State) Output {
+	return Output{Color: f.color}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStrategy("fixed", func(cfg StrategyConfig) (Strategy, error) {
+		return fixedStrategy{color: cfg.palette().Pilot}, nil
+	})
+
+	l := fakeLed{}
+	palette := DefaultPalette
+	palette.Pilot = led.ColorYellow
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", "fixed", WithPalette(palette))
+
+	p.onThrottle(nil, testtools.NewFakeMessageFromProtobuf("throttle", &events.ThrottleMessage{Throttle: -1}))
+	if l.color != led.ColorYellow {
+		t.Errorf("color: %v, wants %v", l.color, led.ColorYellow)
+	}
+	p.onRecord(nil, testtools.NewFakeMessageFromProtobuf("record", &events.SwitchRecordMessage{Enabled: true}))
+	if !l.blink {
+		t.Errorf("led doesn't blink while recording")
+	}
+}
+
+func TestLedPart_InvalidConfig(t *testing.T) {
+	l := fakeLed{}
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", LedModeBrake,
+		WithBrakeLevels([]BrakeLevel{}), WithBrakeHold(time.Second))
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	if l.color != led.ColorBlue {
+		t.Errorf("color: %v, wants default config color %v", l.color, led.ColorBlue)
+	}
+	if err := p.Reload(LedModeBrake, StrategyConfig{BrakeLevels: []BrakeLevel{}}); err == nil {
+		t.Errorf("no error on reload with invalid brake levels")
+	}
+}
+
+func TestLedPart_UnknownMode(t *testing.T) {
+	l := fakeLed{}
+	p := NewPart(nil, &l, "drive", "record", "speedzone", "throttle", "unknown")
+
+	p.onDriveMode(nil, testtools.NewFakeMessageFromProtobuf("drive", &events.DriveModeMessage{DriveMode: events.DriveMode_PILOT}))
+	if l.color != led.ColorBlue {
+		t.Errorf("color: %v, wants brake mode color %v", l.color, led.ColorBlue)
+	}
+}